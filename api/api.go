@@ -44,6 +44,8 @@ type Channel struct {
 	SubscriberCount     int64  `json:"subscriber_count"`
 	VideoCount          int64  `json:"video_count"`
 	IsArchived          bool   `json:"is_archived"`
+	ThumbnailURL        string `json:"thumbnail_url"`
+	BannerURL           string `json:"banner_url,omitempty"` // only included in channel details
 }
 
 type Video struct {
@@ -79,6 +81,85 @@ type Video struct {
 	//Tags             []string        `json:"tags"`
 	//Formats          []YouTubeFormat `json:"formats"`
 	//RequestedFormats []YouTubeFormat `json:"requested_formats"`
+	Chapters  []VideoChapter  `json:"chapters,omitempty"`  // only included in video details
+	Subtitles []VideoSubtitle `json:"subtitles,omitempty"` // only included in video details
+}
+
+type VideoChapter struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Title     string  `json:"title"`
+}
+
+// VideoSubtitle is a subtitle language a video has. Automatic captions are generated by YouTube.
+type VideoSubtitle struct {
+	Language    string   `json:"language"`
+	Name        string   `json:"name"`
+	Formats     []string `json:"formats"`
+	IsAutomatic bool     `json:"is_automatic"`
+}
+
+// VideoComment is a comment saved by yt-dlp. Replies are only included on top level comments.
+type VideoComment struct {
+	YouTubeID        string         `json:"youtube_id"`
+	ParentID         string         `json:"parent_id,omitempty"`
+	Text             string         `json:"text"`
+	Author           string         `json:"author"`
+	AuthorID         string         `json:"author_id"`
+	AuthorThumbnail  string         `json:"author_thumbnail"`
+	AuthorIsUploader bool           `json:"author_is_uploader"`
+	AuthorIsVerified bool           `json:"author_is_verified"`
+	LikeCount        int64          `json:"like_count"`
+	IsFavorited      bool           `json:"is_favorited"`
+	IsPinned         bool           `json:"is_pinned"`
+	PublishedAt      int64          `json:"published_at"`
+	Replies          []VideoComment `json:"replies,omitempty"`
+}
+
+type Playlist struct {
+	ID          int64  `json:"id"`
+	YouTubeID   string `json:"youtube_id"`
+	ChannelID   *int64 `json:"channel_id"` // nil if the channel has not been imported
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	WebpageURL  string `json:"webpage_url"`
+	ModifiedAt  int64  `json:"modified_at"`
+	VideoCount  int64  `json:"video_count"`
+}
+
+// PlaylistVideo is a video in a playlist. Videos that have not been imported only have a YouTube ID and title.
+type PlaylistVideo struct {
+	Position   int64  `json:"position"`
+	YouTubeID  string `json:"youtube_id"`
+	VideoID    *int64 `json:"video_id"`
+	Title      string `json:"title"`
+	IsArchived bool   `json:"is_archived"`
+}
+
+// MissingChannel is a channel that was requested from the YouTube API, but was not returned. This usually means the
+// channel has been deleted or terminated.
+type MissingChannel struct {
+	YouTubeID           string `json:"youtube_id"`
+	ChannelID           *int64 `json:"channel_id"` // nil if we never had data for the channel
+	Title               string `json:"title"`
+	FirstMissingAt      int64  `json:"first_missing_at"`
+	LastCheckedAt       int64  `json:"last_checked_at"`
+	LastSeenAt          *int64 `json:"last_seen_at"`
+	TotalVideosArchived int    `json:"total_videos_archived"`
+}
+
+// mediaURL is the SQL expression for an image URL. Downloaded images are served locally, otherwise the original URL
+// is used.
+const mediaURL = "COALESCE('/media/' || %[1]s.local_path, %[1]s.url, '')"
+
+// channelThumbnailJoin joins the default size channel thumbnail.
+const channelThumbnailJoin = "LEFT JOIN channel_thumbnails ON channel_thumbnails.channel_id = channels.id AND channel_thumbnails.size = 'default'"
+
+type VideoTopic struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	VideoCount int    `json:"video_count"`
 }
 
 type ChannelVideoStats struct {
@@ -116,7 +197,7 @@ LEFT JOIN (SELECT * FROM (
      )
 WHERE rn = 1)
     AS ranked_videos ON channels.id = ranked_videos.channel_id
-         LEFT JOIN (SELECT channel_id, COUNT(*) AS archived_total FROM videos GROUP BY videos.channel_id) archived_videos ON archived_videos.channel_id = channels.id
+         LEFT JOIN (SELECT channel_id, COUNT(*) AS archived_total FROM videos WHERE is_archived GROUP BY videos.channel_id) archived_videos ON archived_videos.channel_id = channels.id
 WHERE channels.id = ?
 `, channelID).Scan(
 		&cvs.ChannelID,
@@ -134,9 +215,15 @@ WHERE channels.id = ?
 	return cvs, nil
 }
 
-func GetVideos(ctx context.Context, db *sql.DB, channelID int, fromTimestamp int) ([]Video, error) {
-	whereClauses := make([]string, 0, 2)
-	whereParams := make([]interface{}, 0, 2)
+// videoColumns are the columns scanned by GetVideos and GetVideo. Videos found in feeds, Takeout, download archives, or
+// imported from the API do not have the yt-dlp columns, and use the publish time as the upload time.
+const videoColumns = `id, youtube_id, COALESCE(title, ''), COALESCE(full_title, title, ''), COALESCE(description, ''), COALESCE(channel_id, ''),
+       COALESCE(width, 0), COALESCE(height, 0), COALESCE(resolution, ''), COALESCE(duration, 0), COALESCE(webpage_url, ''),
+       COALESCE(original_url, webpage_url, ''), COALESCE(uploaded_at, published_at, 0), COALESCE(aspect_ratio, 0)`
+
+func GetVideos(ctx context.Context, db *sql.DB, channelID int, fromTimestamp int, topicID int) ([]Video, error) {
+	whereClauses := make([]string, 0, 3)
+	whereParams := make([]interface{}, 0, 3)
 
 	if channelID > 0 {
 		whereClauses = append(whereClauses, "channel_id = ?")
@@ -144,11 +231,16 @@ func GetVideos(ctx context.Context, db *sql.DB, channelID int, fromTimestamp int
 	}
 
 	if fromTimestamp > 0 {
-		whereClauses = append(whereClauses, "uploaded_at > ?")
+		whereClauses = append(whereClauses, "COALESCE(uploaded_at, published_at) > ?")
 		whereParams = append(whereParams, fromTimestamp)
 	}
 
-	stmt := "SELECT id, youtube_id, title, full_title, description, channel_id, width, height, resolution, duration, webpage_url, original_url, uploaded_at, aspect_ratio FROM videos" // mostly ignoring all of the format related fields
+	if topicID > 0 {
+		whereClauses = append(whereClauses, "id IN (SELECT video_id FROM videos_video_topics WHERE topic_id = ?)")
+		whereParams = append(whereParams, topicID)
+	}
+
+	stmt := "SELECT " + videoColumns + " FROM videos" // mostly ignoring all of the format related fields
 	if len(whereClauses) > 0 {
 		stmt += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
@@ -196,8 +288,303 @@ func GetVideos(ctx context.Context, db *sql.DB, channelID int, fromTimestamp int
 	return videos, nil
 }
 
-func GetChannels(ctx context.Context, db *sql.DB) ([]Channel, error) {
-	rows, err := db.QueryContext(ctx, "SELECT id, youtube_id, title, description, custom_url, branding_title, branding_description, subscriber_count, video_count, is_archived FROM channels")
+func GetVideo(ctx context.Context, db *sql.DB, videoID string) (Video, error) {
+	var v Video
+
+	id, err := strconv.ParseInt(videoID, 10, 64)
+	if err != nil {
+		return v, err
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT "+videoColumns+" FROM videos WHERE id = ?", id).
+		Scan(
+			&v.ID,
+			&v.YouTubeID,
+			&v.Title,
+			&v.FullTitle,
+			&v.Description,
+			&v.ChannelID,
+			&v.Width,
+			&v.Height,
+			&v.Resolution,
+			&v.Duration,
+			&v.WebpageURL,
+			&v.OriginalURL,
+			&v.UploadedAt,
+			&v.AspectRatio)
+	if err != nil {
+		return v, err
+	}
+
+	v.Chapters, err = getVideoChapters(ctx, db, id)
+	if err != nil {
+		return v, err
+	}
+
+	v.Subtitles, err = getVideoSubtitles(ctx, db, id)
+	if err != nil {
+		return v, err
+	}
+
+	return v, nil
+}
+
+func getVideoChapters(ctx context.Context, db *sql.DB, videoID int64) ([]VideoChapter, error) {
+	rows, err := db.QueryContext(ctx, "SELECT start_time, end_time, title FROM video_chapters WHERE video_id = ? ORDER BY position", videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chapters []VideoChapter
+	for rows.Next() {
+		c := VideoChapter{}
+
+		if err := rows.Scan(&c.StartTime, &c.EndTime, &c.Title); err != nil {
+			return nil, err
+		}
+
+		chapters = append(chapters, c)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return chapters, nil
+}
+
+func getVideoSubtitles(ctx context.Context, db *sql.DB, videoID int64) ([]VideoSubtitle, error) {
+	rows, err := db.QueryContext(ctx, "SELECT language, COALESCE(name, ''), formats, is_automatic FROM video_subtitles WHERE video_id = ? ORDER BY is_automatic, language", videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subtitles []VideoSubtitle
+	for rows.Next() {
+		s := VideoSubtitle{}
+
+		var formats string
+		if err := rows.Scan(&s.Language, &s.Name, &formats, &s.IsAutomatic); err != nil {
+			return nil, err
+		}
+		s.Formats = strings.Split(formats, ",")
+
+		subtitles = append(subtitles, s)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subtitles, nil
+}
+
+// GetVideoComments gets the comments on a video as threads. Comments are in the order yt-dlp saved them.
+func GetVideoComments(ctx context.Context, db *sql.DB, videoID string) ([]VideoComment, error) {
+	id, err := strconv.ParseInt(videoID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT youtube_id, COALESCE(parent_youtube_id, ''), COALESCE(text, ''), COALESCE(author, ''), COALESCE(author_id, ''),
+       COALESCE(author_thumbnail, ''), COALESCE(author_is_uploader, FALSE), COALESCE(author_is_verified, FALSE), COALESCE(like_count, 0),
+       COALESCE(is_favorited, FALSE), COALESCE(is_pinned, FALSE), COALESCE(published_at, 0)
+FROM video_comments
+WHERE video_id = ?
+ORDER BY position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []VideoComment
+	for rows.Next() {
+		c := VideoComment{}
+
+		if err := rows.Scan(
+			&c.YouTubeID,
+			&c.ParentID,
+			&c.Text,
+			&c.Author,
+			&c.AuthorID,
+			&c.AuthorThumbnail,
+			&c.AuthorIsUploader,
+			&c.AuthorIsVerified,
+			&c.LikeCount,
+			&c.IsFavorited,
+			&c.IsPinned,
+			&c.PublishedAt); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, c)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	replies := make(map[string][]VideoComment)
+	topLevel := make(map[string]bool)
+	for _, c := range comments {
+		if c.ParentID == "" {
+			topLevel[c.YouTubeID] = true
+		} else {
+			replies[c.ParentID] = append(replies[c.ParentID], c)
+		}
+	}
+
+	threads := make([]VideoComment, 0)
+	for _, c := range comments {
+		// replies to comments that were not saved are shown as top level comments
+		if c.ParentID != "" && topLevel[c.ParentID] {
+			continue
+		}
+
+		c.Replies = replies[c.YouTubeID]
+		threads = append(threads, c)
+	}
+
+	return threads, nil
+}
+
+// playlistColumns are the columns scanned by scanPlaylist.
+const playlistColumns = `playlists.id, youtube_id, channel_id, COALESCE(title, ''), COALESCE(description, ''), COALESCE(webpage_url, ''), COALESCE(modified_at, 0),
+       (SELECT COUNT(*) FROM playlist_videos WHERE playlist_id = playlists.id)`
+
+func scanPlaylist(row interface{ Scan(...any) error }) (Playlist, error) {
+	var p Playlist
+	err := row.Scan(
+		&p.ID,
+		&p.YouTubeID,
+		&p.ChannelID,
+		&p.Title,
+		&p.Description,
+		&p.WebpageURL,
+		&p.ModifiedAt,
+		&p.VideoCount)
+
+	return p, err
+}
+
+func GetPlaylists(ctx context.Context, db *sql.DB, channelID int) ([]Playlist, error) {
+	stmt := "SELECT " + playlistColumns + " FROM playlists"
+	params := make([]any, 0, 1)
+	if channelID > 0 {
+		stmt += " WHERE channel_id = ?"
+		params = append(params, channelID)
+	}
+	stmt += " ORDER BY title COLLATE NOCASE"
+
+	rows, err := db.QueryContext(ctx, stmt, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var playlists []Playlist
+	for rows.Next() {
+		p, err := scanPlaylist(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		playlists = append(playlists, p)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return playlists, nil
+}
+
+func GetPlaylist(ctx context.Context, db *sql.DB, playlistID string) (Playlist, error) {
+	id, err := strconv.ParseInt(playlistID, 10, 64)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	return scanPlaylist(db.QueryRowContext(ctx, "SELECT "+playlistColumns+" FROM playlists WHERE id = ?", id))
+}
+
+// GetPlaylistVideos gets the videos in a playlist in playlist order.
+func GetPlaylistVideos(ctx context.Context, db *sql.DB, playlistID string) ([]PlaylistVideo, error) {
+	id, err := strconv.ParseInt(playlistID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT playlist_videos.position, playlist_videos.video_youtube_id, videos.id,
+       COALESCE(videos.title, playlist_videos.title, ''), COALESCE(videos.is_archived, FALSE)
+FROM playlist_videos
+LEFT JOIN videos ON videos.youtube_id = playlist_videos.video_youtube_id
+WHERE playlist_videos.playlist_id = ?
+ORDER BY playlist_videos.position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []PlaylistVideo
+	for rows.Next() {
+		v := PlaylistVideo{}
+
+		if err := rows.Scan(&v.Position, &v.YouTubeID, &v.VideoID, &v.Title, &v.IsArchived); err != nil {
+			return nil, err
+		}
+
+		videos = append(videos, v)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+// channelColumns are the columns scanned by GetChannels and GetChannel, followed by the thumbnail URL. Channels imported
+// from a subscriptions file or Takeout only have an ID and title until they are imported from the API.
+const channelColumns = `channels.id, youtube_id, COALESCE(title, ''), COALESCE(description, ''), COALESCE(custom_url, ''), COALESCE(branding_title, ''),
+       COALESCE(branding_description, ''), subscriber_count, video_count, COALESCE(is_archived, FALSE)`
+
+// GetChannels gets all the channels, or only the channels in a group if group is not empty.
+func GetChannels(ctx context.Context, db *sql.DB, group string) ([]Channel, error) {
+	stmt := fmt.Sprintf(`SELECT %s, %s FROM channels %s`,
+		channelColumns,
+		fmt.Sprintf(mediaURL, "channel_thumbnails"),
+		channelThumbnailJoin)
+
+	params := make([]any, 0, 1)
+	if group != "" {
+		stmt += " WHERE channels.id IN (SELECT channel_id FROM channel_groups_channels JOIN channel_groups ON channel_groups.id = group_id WHERE channel_groups.name = ?)"
+		params = append(params, group)
+	}
+
+	rows, err := db.QueryContext(ctx, stmt, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -217,7 +604,8 @@ func GetChannels(ctx context.Context, db *sql.DB) ([]Channel, error) {
 			&c.BrandingDescription,
 			&c.SubscriberCount,
 			&c.VideoCount,
-			&c.IsArchived); err != nil {
+			&c.IsArchived,
+			&c.ThumbnailURL); err != nil {
 			return nil, err
 		}
 
@@ -243,7 +631,15 @@ func GetChannel(ctx context.Context, db *sql.DB, channelID string) (Channel, err
 		return c, err
 	}
 
-	err = db.QueryRowContext(ctx, "SELECT id, youtube_id, title, description, custom_url, branding_title, branding_description, subscriber_count, video_count, is_archived FROM channels WHERE id = ?", id).
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s, %s, %s
+FROM channels
+%s
+LEFT JOIN channel_banners ON channel_banners.channel_id = channels.id
+WHERE channels.id = ?`,
+		channelColumns,
+		fmt.Sprintf(mediaURL, "channel_thumbnails"),
+		fmt.Sprintf(mediaURL, "channel_banners"),
+		channelThumbnailJoin), id).
 		Scan(
 			&c.ID,
 			&c.YouTubeID,
@@ -254,10 +650,100 @@ func GetChannel(ctx context.Context, db *sql.DB, channelID string) (Channel, err
 			&c.BrandingDescription,
 			&c.SubscriberCount,
 			&c.VideoCount,
-			&c.IsArchived)
+			&c.IsArchived,
+			&c.ThumbnailURL,
+			&c.BannerURL)
 	if err != nil {
 		return c, err
 	}
 
 	return c, nil
 }
+
+func GetMissingChannels(ctx context.Context, db *sql.DB) ([]MissingChannel, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT
+    channel_tombstones.youtube_id,
+    channel_tombstones.channel_id,
+    COALESCE(channels.title, ''),
+    channel_tombstones.first_missing_at,
+    channel_tombstones.last_checked_at,
+    channel_tombstones.last_seen_at,
+    COALESCE(archived_videos.archived_total, 0) AS total_videos_archived
+FROM channel_tombstones
+LEFT JOIN channels ON channels.id = channel_tombstones.channel_id
+LEFT JOIN (SELECT channel_id, COUNT(*) AS archived_total FROM videos WHERE is_archived GROUP BY videos.channel_id) archived_videos ON archived_videos.channel_id = channel_tombstones.channel_id
+ORDER BY channel_tombstones.first_missing_at DESC
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []MissingChannel
+	for rows.Next() {
+		c := MissingChannel{}
+
+		if err := rows.Scan(
+			&c.YouTubeID,
+			&c.ChannelID,
+			&c.Title,
+			&c.FirstMissingAt,
+			&c.LastCheckedAt,
+			&c.LastSeenAt,
+			&c.TotalVideosArchived); err != nil {
+			return nil, err
+		}
+
+		channels = append(channels, c)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+func GetVideoTopics(ctx context.Context, db *sql.DB) ([]VideoTopic, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT video_topics.id, COALESCE(video_topics.name, ''), COALESCE(video_topics.url, ''), COUNT(videos_video_topics.video_id)
+FROM video_topics
+LEFT JOIN videos_video_topics ON videos_video_topics.topic_id = video_topics.id
+GROUP BY video_topics.id
+ORDER BY video_topics.name
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var topics []VideoTopic
+	for rows.Next() {
+		t := VideoTopic{}
+
+		if err := rows.Scan(
+			&t.ID,
+			&t.Name,
+			&t.URL,
+			&t.VideoCount); err != nil {
+			return nil, err
+		}
+
+		topics = append(topics, t)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return topics, nil
+}
@@ -0,0 +1,136 @@
+package api_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/api"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// openTestDB creates a database with every migration applied.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", filepath.Join(t.TempDir(), "youtube.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://../db/migrations", "sqlite3", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) int64 {
+	t.Helper()
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// TestGetVideosPartialRows checks that videos saved by importers other than yt-dlp, which leave most columns NULL, can
+// be listed, filtered, and shown.
+func TestGetVideosPartialRows(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	channelID := mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+
+	// saved like saveAPIVideo
+	apiVideoID := mustExec(t, db, "INSERT INTO videos(youtube_id, published_at, channel_id, title, description, duration, webpage_url) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		"apivideo001", 1700000000, channelID, "API video", "From the API", 61, "https://www.youtube.com/watch?v=apivideo001")
+	topicID := mustExec(t, db, "INSERT INTO video_topics(name, url) VALUES(?, ?)", "Music", "https://en.wikipedia.org/wiki/Music")
+	mustExec(t, db, "INSERT INTO videos_video_topics(video_id, topic_id) VALUES(?, ?)", apiVideoID, topicID)
+
+	// saved like the Takeout and download archive importers
+	mustExec(t, db, "INSERT INTO videos(youtube_id, title, channel_id, webpage_url) VALUES(?, ?, ?, ?)",
+		"takeout0001", "Takeout video", channelID, "https://www.youtube.com/watch?v=takeout0001")
+
+	videos, err := api.GetVideos(ctx, db, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("GetVideos: %s", err)
+	}
+	if len(videos) != 2 {
+		t.Fatalf("GetVideos returned %d videos, want 2", len(videos))
+	}
+
+	videos, err = api.GetVideos(ctx, db, 0, 0, int(topicID))
+	if err != nil {
+		t.Fatalf("GetVideos by topic: %s", err)
+	}
+	if len(videos) != 1 || videos[0].YouTubeID != "apivideo001" {
+		t.Fatalf("GetVideos by topic returned %+v, want apivideo001", videos)
+	}
+
+	v := videos[0]
+	if v.FullTitle != "API video" || v.UploadedAt != 1700000000 || v.OriginalURL != "https://www.youtube.com/watch?v=apivideo001" {
+		t.Errorf("missing columns were not filled in from the API columns: %+v", v)
+	}
+
+	videos, err = api.GetVideos(ctx, db, 0, 1600000000, 0)
+	if err != nil {
+		t.Fatalf("GetVideos from timestamp: %s", err)
+	}
+	if len(videos) != 1 {
+		t.Errorf("GetVideos from timestamp returned %d videos, want 1", len(videos))
+	}
+
+	_, err = api.GetVideo(ctx, db, fmt.Sprint(apiVideoID))
+	if err != nil {
+		t.Fatalf("GetVideo: %s", err)
+	}
+}
+
+// TestGetChannelPartialRow checks that a channel imported from Takeout or a subscriptions file, which only has an ID
+// and title, can be shown.
+func TestGetChannelPartialRow(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	channelID := mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+
+	c, err := api.GetChannel(ctx, db, fmt.Sprint(channelID))
+	if err != nil {
+		t.Fatalf("GetChannel: %s", err)
+	}
+	if c.Title != "Channel" || c.Description != "" {
+		t.Errorf("GetChannel returned %+v", c)
+	}
+
+	channels, err := api.GetChannels(ctx, db, "")
+	if err != nil {
+		t.Fatalf("GetChannels: %s", err)
+	}
+	if len(channels) != 1 {
+		t.Errorf("GetChannels returned %d channels, want 1", len(channels))
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// subscription export formats
+const (
+	ExportOPML    = "opml"
+	ExportNewPipe = "newpipe"
+	ExportCSV     = "csv"
+)
+
+// ExportFormats are the content type and file extension of each export format.
+var ExportFormats = map[string]struct {
+	ContentType string
+	Extension   string
+}{
+	ExportOPML:    {"text/x-opml; charset=utf-8", ".opml"},
+	ExportNewPipe: {"application/json", ".json"},
+	ExportCSV:     {"text/csv; charset=utf-8", ".csv"},
+}
+
+// newPipeAppVersion is the NewPipe version the export claims to be from. NewPipe checks that the field exists, but
+// imports files from any version.
+const (
+	newPipeAppVersion    = "0.27.0"
+	newPipeAppVersionInt = 1000
+)
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+// ChannelURL is the URL of the channel page on YouTube.
+func ChannelURL(youtubeID string) string {
+	return "https://www.youtube.com/channel/" + youtubeID
+}
+
+// ChannelFeedURL is the URL of the channel's RSS feed on YouTube.
+func ChannelFeedURL(youtubeID string) string {
+	return "https://www.youtube.com/feeds/videos.xml?channel_id=" + youtubeID
+}
+
+// WriteSubscriptions writes the channels in a format other apps can import. OPML uses each channel's RSS feed,
+// NewPipe uses the NewPipe subscriptions JSON, and CSV matches the subscriptions.csv file from Google Takeout.
+func WriteSubscriptions(w io.Writer, format string, title string, channels []Channel) error {
+	switch format {
+	case ExportOPML:
+		outlines := make([]opmlOutline, 0, len(channels))
+		for _, c := range channels {
+			outlines = append(outlines, opmlOutline{
+				Text:    c.Title,
+				Title:   c.Title,
+				Type:    "rss",
+				XMLURL:  ChannelFeedURL(c.YouTubeID),
+				HTMLURL: ChannelURL(c.YouTubeID),
+			})
+		}
+
+		doc := opml{
+			Version: "1.1",
+			Title:   title,
+			Body:    []opmlOutline{{Text: title, Title: title, Outlines: outlines}},
+		}
+
+		_, err := io.WriteString(w, xml.Header)
+		if err != nil {
+			return err
+		}
+
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		err = enc.Encode(doc)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, "\n")
+		return err
+	case ExportNewPipe:
+		type subscription struct {
+			ServiceID int    `json:"service_id"`
+			URL       string `json:"url"`
+			Name      string `json:"name"`
+		}
+
+		export := struct {
+			AppVersion    string         `json:"app_version"`
+			AppVersionInt int            `json:"app_version_int"`
+			Subscriptions []subscription `json:"subscriptions"`
+		}{
+			AppVersion:    newPipeAppVersion,
+			AppVersionInt: newPipeAppVersionInt,
+			Subscriptions: make([]subscription, 0, len(channels)),
+		}
+
+		for _, c := range channels {
+			export.Subscriptions = append(export.Subscriptions, subscription{URL: ChannelURL(c.YouTubeID), Name: c.Title})
+		}
+
+		return json.NewEncoder(w).Encode(export)
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"Channel Id", "Channel Url", "Channel Title"})
+		for _, c := range channels {
+			// Takeout uses http URLs
+			_ = cw.Write([]string{c.YouTubeID, "http://www.youtube.com/channel/" + c.YouTubeID, c.Title})
+		}
+		cw.Flush()
+
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown export format: %s", format)
+	}
+}
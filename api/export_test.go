@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/api"
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+// TestWriteSubscriptions checks each export format, and that the importer reads the same channels back.
+func TestWriteSubscriptions(t *testing.T) {
+	channels := []api.Channel{
+		{YouTubeID: "UC0000000000000000000001", Title: "Tom & Jerry"},
+		{YouTubeID: "UC0000000000000000000002", Title: `Say "hi", ok`},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{api.ExportOPML, []string{
+			`<?xml version="1.0" encoding="UTF-8"?>`,
+			`<title>Favorites</title>`,
+			`<outline text="Tom &amp; Jerry" title="Tom &amp; Jerry" type="rss" xmlUrl="https://www.youtube.com/feeds/videos.xml?channel_id=UC0000000000000000000001" htmlUrl="https://www.youtube.com/channel/UC0000000000000000000001"></outline>`,
+		}},
+		{api.ExportNewPipe, []string{
+			`"app_version":"0.27.0"`,
+			`{"service_id":0,"url":"https://www.youtube.com/channel/UC0000000000000000000001","name":"Tom \u0026 Jerry"}`,
+		}},
+		{api.ExportCSV, []string{
+			"Channel Id,Channel Url,Channel Title\n",
+			"UC0000000000000000000001,http://www.youtube.com/channel/UC0000000000000000000001,Tom & Jerry\n",
+			`UC0000000000000000000002,http://www.youtube.com/channel/UC0000000000000000000002,"Say ""hi"", ok"` + "\n",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := api.WriteSubscriptions(&buf, test.format, "Favorites", channels)
+			if err != nil {
+				t.Fatalf("WriteSubscriptions: %s", err)
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("export does not contain %s:\n%s", want, buf.String())
+				}
+			}
+
+			file := filepath.Join(t.TempDir(), "subscriptions"+api.ExportFormats[test.format].Extension)
+			err = os.WriteFile(file, buf.Bytes(), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids, err := importer.ReadSubscriptions(file)
+			if err != nil {
+				t.Fatalf("ReadSubscriptions: %s", err)
+			}
+			if want := []string{channels[0].YouTubeID, channels[1].YouTubeID}; !slices.Equal(ids, want) {
+				t.Errorf("export was read back as %v, want %v", ids, want)
+			}
+		})
+	}
+
+	err := api.WriteSubscriptions(&bytes.Buffer{}, "xml", "Favorites", channels)
+	if err == nil {
+		t.Error("WriteSubscriptions did not return an error for an unknown format")
+	}
+}
+
+// TestGetChannelsInGroup checks that exports can be limited to a channel group.
+func TestGetChannelsInGroup(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "First")
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000002", "Second")
+
+	added, err := api.AddToChannelGroup(ctx, db, "favorites", []string{"UC0000000000000000000002", "UC0000000000000000000009"})
+	if err != nil {
+		t.Fatalf("AddToChannelGroup: %s", err)
+	}
+	if added != 1 {
+		t.Errorf("AddToChannelGroup added %d channels, want 1", added)
+	}
+
+	channels, err := api.GetChannels(ctx, db, "favorites")
+	if err != nil {
+		t.Fatalf("GetChannels: %s", err)
+	}
+	if len(channels) != 1 || channels[0].YouTubeID != "UC0000000000000000000002" {
+		t.Errorf("GetChannels in group returned %+v", channels)
+	}
+}
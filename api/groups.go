@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type ChannelGroup struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	ChannelCount int64  `json:"channel_count"`
+}
+
+func GetChannelGroups(ctx context.Context, db *sql.DB) ([]ChannelGroup, error) {
+	rows, err := db.QueryContext(ctx, `SELECT channel_groups.id, channel_groups.name, COUNT(channel_groups_channels.channel_id)
+FROM channel_groups
+LEFT JOIN channel_groups_channels ON channel_groups_channels.group_id = channel_groups.id
+GROUP BY channel_groups.id
+ORDER BY channel_groups.name COLLATE NOCASE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []ChannelGroup
+	for rows.Next() {
+		g := ChannelGroup{}
+
+		if err := rows.Scan(&g.ID, &g.Name, &g.ChannelCount); err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, g)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// AddToChannelGroup adds channels to a group, creating the group if needed. Channels are given by YouTube ID, and
+// channels that have not been imported are skipped. The number of channels added to the group is returned.
+func AddToChannelGroup(ctx context.Context, db *sql.DB, group string, youtubeIDs []string) (int, error) {
+	_, err := db.ExecContext(ctx, "INSERT INTO channel_groups(name) VALUES(?)", group)
+	if err != nil {
+		return 0, fmt.Errorf("could not create group: %s : %w", group, err)
+	}
+
+	added := 0
+	for _, id := range youtubeIDs {
+		result, err := db.ExecContext(ctx, `INSERT INTO channel_groups_channels(group_id, channel_id)
+			SELECT channel_groups.id, channels.id FROM channel_groups, channels WHERE channel_groups.name = ? AND channels.youtube_id = ?`,
+			group, id)
+		if err != nil {
+			return added, fmt.Errorf("could not add channel to group: %s : %w", id, err)
+		}
+
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+// RemoveFromChannelGroup removes channels from a group. The group is deleted if no channels are given.
+func RemoveFromChannelGroup(ctx context.Context, db *sql.DB, group string, youtubeIDs []string) error {
+	if len(youtubeIDs) == 0 {
+		_, err := db.ExecContext(ctx, "DELETE FROM channel_groups WHERE name = ?", group)
+		return err
+	}
+
+	for _, id := range youtubeIDs {
+		_, err := db.ExecContext(ctx, `DELETE FROM channel_groups_channels
+			WHERE group_id = (SELECT id FROM channel_groups WHERE name = ?)
+			  AND channel_id = (SELECT id FROM channels WHERE youtube_id = ?)`, group, id)
+		if err != nil {
+			return fmt.Errorf("could not remove channel from group: %s : %w", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+type AuthCmd struct{}
+
+func (ac *AuthCmd) Run(ctx *Context) error {
+	tokenFile := ctx.TokenFile
+	if tokenFile == "" {
+		tokenFile = importer.DefaultTokenFile
+	}
+
+	_, err := importer.Authorize(context.Background(), ctx.SecretFile, tokenFile)
+	return err
+}
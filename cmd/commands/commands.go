@@ -1,21 +1,76 @@
 package commands
 
-import youtube_subscription_browser "github.com/WileESpaghetti/youtube-subscription-browser"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+	_ "github.com/mattn/go-sqlite3"
+)
 
 type Context struct {
 	Verbose      bool   `help:"Enable verbose mode."`
 	Database     string `help:"Database file." default:"./youtube.sqlite"`
+	SecretFile   string `help:"OAuth client secret." default:"client_secret.json"`
 	TokenFile    string `help:"OAuth authorization token." default:""`
 	CacheDir     string `help:"Cache directory." default:"./cache"`
+	MediaDir     string `help:"Directory for downloaded thumbnails and banners." default:"./media"`
 	DisableCache bool   `help:"Disable cache."`
+	RefreshCache bool   `help:"Ignore cached API responses, but save new ones to the cache."`
+	QuotaBudget  int    `help:"Maximum YouTube Data API quota units to use per day." default:"10000"`
+	FeedURL      string `help:"Base URL of the channel RSS feeds." default:"https://www.youtube.com/feeds/videos.xml"`
 }
 
 func NewContext() *Context {
 	return &Context{
 		Verbose:      false,
 		Database:     "./youtube.sqlite",
-		TokenFile:    youtube_subscription_browser.DefaultTokenFile,
+		SecretFile:   importer.DefaultSecretFile,
+		TokenFile:    importer.DefaultTokenFile,
 		CacheDir:     "./cache",
+		MediaDir:     importer.DefaultMediaDir,
 		DisableCache: false,
+		RefreshCache: false,
+		QuotaBudget:  importer.DefaultQuotaBudget,
+		FeedURL:      importer.DefaultFeedURL,
+	}
+}
+
+// OpenDatabase opens the database with foreign keys enabled. The server's jobs can write to the database while a
+// command is running, so it waits for locks instead of failing right away.
+func (c *Context) OpenDatabase() (*sql.DB, error) {
+	return sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", c.Database))
+}
+
+// Cache creates the cache selected by the cache flags.
+func (c *Context) Cache() importer.Cache {
+	switch {
+	case c.DisableCache:
+		return importer.NewNullCache()
+	case c.RefreshCache:
+		return importer.NewRefreshCache(importer.NewFileCache(c.CacheDir))
+	default:
+		return importer.NewFileCache(c.CacheDir)
+	}
+}
+
+// YouTube creates a cached YouTube Data API client that counts calls against today's quota.
+func (c *Context) YouTube(ctx context.Context, db *sql.DB) (*importer.YouTube, error) {
+	tokenFile := c.TokenFile
+	if tokenFile == "" {
+		tokenFile = importer.DefaultTokenFile
+	}
+
+	service, err := importer.NewYouTubeService(ctx, c.SecretFile, tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create YouTube client: %w", err)
 	}
+
+	quota, err := importer.NewQuota(ctx, db, c.QuotaBudget)
+	if err != nil {
+		return nil, err
+	}
+
+	return importer.NewYouTube(service, c.Cache(), quota), nil
 }
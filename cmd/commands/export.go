@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/api"
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+type ExportCmd struct {
+	Subscriptions ExportSubscriptionsCmd `cmd:"" help:"Export channels so they can be imported by other apps."`
+	All           ExportAllCmd           `cmd:"" help:"Back up the whole database as JSON Lines."`
+}
+
+type ExportSubscriptionsCmd struct {
+	Format string `help:"Export format." enum:"opml,newpipe,csv" default:"opml"`
+	Group  string `help:"Only export the channels in this group."`
+	Output string `short:"o" help:"File to write to. Defaults to stdout." type:"path"`
+}
+
+func (ec *ExportSubscriptionsCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	channels, err := api.GetChannels(context.Background(), db, ec.Group)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if ec.Output != "" {
+		f, err := os.Create(ec.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	title := "YouTube Subscriptions"
+	if ec.Group != "" {
+		title = ec.Group
+	}
+
+	return api.WriteSubscriptions(w, ec.Format, title, channels)
+}
+
+type ExportAllCmd struct {
+	Backup string `arg:"" help:"Directory to write the backup to, or a .zip file." type:"path"`
+}
+
+func (ec *ExportAllCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.WriteBackup(context.Background(), db, ec.Backup)
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/api"
+)
+
+type GroupCmd struct {
+	List   GroupListCmd   `cmd:"" help:"List groups, or the channels in a group."`
+	Add    GroupAddCmd    `cmd:"" help:"Add channels to a group. The group is created if it does not exist."`
+	Remove GroupRemoveCmd `cmd:"" help:"Remove channels from a group, or delete the group if no channels are given."`
+}
+
+type GroupListCmd struct {
+	Name string `arg:"" optional:"" help:"Group to list the channels of."`
+}
+
+func (gc *GroupListCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := context.Background()
+
+	if gc.Name == "" {
+		groups, err := api.GetChannelGroups(c, db)
+		if err != nil {
+			return err
+		}
+
+		for _, g := range groups {
+			fmt.Printf("%s (%d channels)\n", g.Name, g.ChannelCount)
+		}
+		return nil
+	}
+
+	channels, err := api.GetChannels(c, db, gc.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range channels {
+		fmt.Printf("%s %s\n", ch.YouTubeID, ch.Title)
+	}
+	return nil
+}
+
+type GroupAddCmd struct {
+	Name     string   `arg:"" help:"Group name."`
+	Channels []string `arg:"" help:"YouTube IDs of the channels to add."`
+}
+
+func (gc *GroupAddCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	added, err := api.AddToChannelGroup(context.Background(), db, gc.Name, gc.Channels)
+	fmt.Printf("added %d channels to %s\n", added, gc.Name)
+
+	return err
+}
+
+type GroupRemoveCmd struct {
+	Name     string   `arg:"" help:"Group name."`
+	Channels []string `arg:"" optional:"" help:"YouTube IDs of the channels to remove."`
+}
+
+func (gc *GroupRemoveCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return api.RemoveFromChannelGroup(context.Background(), db, gc.Name, gc.Channels)
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+type ImportCmd struct {
+	Subscriptions ImportSubscriptionsCmd `cmd:"" default:"1" help:"Import the channels you are subscribed to."`
+	Channels      ImportChannelsCmd      `cmd:"" help:"Import channels from a subscriptions export (Takeout CSV, NewPipe, FreeTube, Invidious, or OPML)."`
+	Uploads       ImportUploadsCmd       `cmd:"" help:"Import the videos uploaded by channels."`
+	YTDLP         ImportYTDLPCmd         `cmd:"" name:"ytdlp" help:"Import videos from the info.json files written by yt-dlp."`
+	Archive       ImportArchiveCmd       `cmd:"" help:"Mark the videos in a yt-dlp download archive as archived."`
+	Takeout       ImportTakeoutCmd       `cmd:"" help:"Import subscriptions, playlists, and history from a Google Takeout zip file."`
+	All           ImportAllCmd           `cmd:"" help:"Restore a backup written by export all."`
+}
+
+type ImportSubscriptionsCmd struct{}
+
+func (ic *ImportSubscriptionsCmd) Run(ctx *Context) error {
+	return runYouTubeImport(ctx, func(c context.Context, yi *importer.YouTubeImporter) error {
+		return yi.ImportSubscriptions(c)
+	})
+}
+
+type ImportChannelsCmd struct {
+	File string `arg:"" help:"Subscriptions export. CSV files need a header row and the channel ID in the first column." type:"existingfile"`
+}
+
+func (ic *ImportChannelsCmd) Run(ctx *Context) error {
+	channels, err := importer.ReadSubscriptions(ic.File)
+	if err != nil {
+		return fmt.Errorf("can not read input file: '%s': %w", ic.File, err)
+	}
+	fmt.Printf("channel count: %d\n", len(channels))
+
+	return runYouTubeImport(ctx, func(c context.Context, yi *importer.YouTubeImporter) error {
+		return yi.ImportChannels(c, channels)
+	})
+}
+
+type ImportUploadsCmd struct {
+	Channels []string `arg:"" optional:"" help:"YouTube IDs of the channels to import. Defaults to every channel in the database."`
+}
+
+func (ic *ImportUploadsCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := context.Background()
+
+	yt, err := ctx.YouTube(c, db)
+	if err != nil {
+		return err
+	}
+
+	return importer.NewYouTubeImporter(db, yt).ImportUploads(c, ic.Channels)
+}
+
+type ImportYTDLPCmd struct {
+	Paths   []string `arg:"" help:"Directories to search for info.json files, single JSON files, .zip or .tar(.gz) archives, or - to read JSON from stdin."`
+	Workers int      `help:"Number of files to parse at the same time. Defaults to one per CPU."`
+}
+
+func (ic *ImportYTDLPCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.NewYTDLPImporter(db, ic.Workers).Import(context.Background(), ic.Paths...)
+}
+
+type ImportArchiveCmd struct {
+	File   string `arg:"" help:"Download archive written by yt-dlp --download-archive." type:"existingfile"`
+	Lookup bool   `help:"Look up videos that are not in the database with the YouTube Data API. Otherwise only cached API responses are used."`
+}
+
+func (ic *ImportArchiveCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := context.Background()
+
+	var yt *importer.YouTube
+	if ic.Lookup {
+		yt, err = ctx.YouTube(c, db)
+		if err != nil {
+			return err
+		}
+	}
+
+	return importer.NewDownloadArchiveImporter(db, ctx.Cache(), yt).Import(c, ic.File)
+}
+
+type ImportTakeoutCmd struct {
+	File string `arg:"" help:"Google Takeout zip file with YouTube data." type:"existingfile"`
+}
+
+func (ic *ImportTakeoutCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.NewTakeoutImporter(db).Import(context.Background(), ic.File)
+}
+
+type ImportAllCmd struct {
+	Backup string `arg:"" help:"Backup directory or zip file." type:"existingpath"`
+}
+
+func (ic *ImportAllCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.NewBackupImporter(db).Import(context.Background(), ic.Backup)
+}
+
+// runYouTubeImport finishes any channel import that was stopped early before starting a new one.
+func runYouTubeImport(ctx *Context, run func(context.Context, *importer.YouTubeImporter) error) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := context.Background()
+
+	yt, err := ctx.YouTube(c, db)
+	if err != nil {
+		return err
+	}
+
+	yi := importer.NewYouTubeImporter(db, yt)
+
+	resumed, err := yi.Resume(c)
+	if err != nil {
+		return err
+	}
+	if resumed {
+		fmt.Println("Finished the previous import, starting the new one.")
+	}
+
+	return run(c, yi)
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+type ScanCmd struct {
+	Paths  []string `arg:"" help:"Directories with the info.json and media files downloaded by yt-dlp." type:"existingdir"`
+	Update bool     `help:"Record files that changed since they were scanned as they are now, like after downloading them again."`
+}
+
+func (sc *ScanCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.NewMediaLibrary(db).Scan(context.Background(), sc.Update, sc.Paths...)
+}
+
+type VerifyCmd struct {
+	Checksum bool `help:"Compare checksums too. This reads every media file."`
+}
+
+func (vc *VerifyCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	problems, err := importer.NewMediaLibrary(db).Verify(context.Background(), vc.Checksum)
+	for _, p := range problems {
+		fmt.Printf("%s: %s (Video ID: %s)\n", p.Status, p.Path, p.YouTubeID)
+	}
+	fmt.Printf("%d media files have problems\n", len(problems))
+
+	return err
+}
@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+type MirrorCmd struct{}
+
+func (mc *MirrorCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.NewThumbnailMirror(db, ctx.MediaDir).Mirror(context.Background())
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+type PollCmd struct {
+	Channels []string `arg:"" optional:"" help:"YouTube IDs of the channels to check. Defaults to every channel in the database."`
+}
+
+func (pc *PollCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return importer.NewFeedPoller(db, ctx.FeedURL).Poll(context.Background(), pc.Channels)
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+	"github.com/WileESpaghetti/youtube-subscription-browser/server"
+)
+
+type ServeCmd struct {
+	Listen           string        `help:"Address to serve the API and frontend on." default:":8080"`
+	Frontend         string        `help:"Directory with the built frontend." default:"./frontend/dist"`
+	PollFeeds        time.Duration `help:"How often to check channel RSS feeds for new uploads." default:"1h"`
+	RefreshStats     time.Duration `help:"How often to refresh channel statistics with the YouTube Data API." default:"24h"`
+	ImportUploads    time.Duration `help:"How often to import every channel's uploads with the YouTube Data API, which finds videos that were made private or deleted." default:"168h"`
+	RescanMedia      time.Duration `help:"How often to scan the media library for new files." default:"24h"`
+	MirrorThumbnails time.Duration `help:"How often to download new thumbnails and banners." default:"6h"`
+	JobJitter        time.Duration `help:"Random delay added to each job's interval." default:"5m"`
+	MediaLibrary     []string      `help:"Directories with media downloaded by yt-dlp, scanned by the rescan_media job."`
+}
+
+func (sc *ServeCmd) Run(ctx *Context) error {
+	db, err := ctx.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	tokenFile := ctx.TokenFile
+	if tokenFile == "" {
+		tokenFile = importer.DefaultTokenFile
+	}
+
+	return server.Run(c, db, server.Config{
+		Addr:             sc.Listen,
+		FrontendDir:      sc.Frontend,
+		MediaDir:         ctx.MediaDir,
+		SecretFile:       ctx.SecretFile,
+		TokenFile:        tokenFile,
+		Cache:            ctx.Cache(),
+		QuotaBudget:      ctx.QuotaBudget,
+		FeedURL:          ctx.FeedURL,
+		PollFeeds:        sc.PollFeeds,
+		RefreshStats:     sc.RefreshStats,
+		ImportUploads:    sc.ImportUploads,
+		RescanMedia:      sc.RescanMedia,
+		MirrorThumbnails: sc.MirrorThumbnails,
+		Jitter:           sc.JobJitter,
+		MediaLibrary:     sc.MediaLibrary,
+	})
+}
@@ -10,7 +10,15 @@ var cli struct {
 
 	Auth   commands.AuthCmd   `cmd:"" help:"Authenticate with YouTube Data API"`
 	Import commands.ImportCmd `cmd:"" help:"Import"`
+	Export commands.ExportCmd `cmd:"" help:"Export"`
+	Group  commands.GroupCmd  `cmd:"" help:"Manage channel groups."`
 	InitDB commands.InitDBCmd `cmd:"" help:"init-db"`
+	Mirror commands.MirrorCmd `cmd:"" help:"Download thumbnails and banners so they can be served locally."`
+	Notify commands.NotifyCmd `cmd:"" help:"Manage notification rules."`
+	Poll   commands.PollCmd   `cmd:"" help:"Check channel RSS feeds for new uploads without using API quota."`
+	Scan   commands.ScanCmd   `cmd:"" help:"Find the media files downloaded by yt-dlp."`
+	Serve  commands.ServeCmd  `cmd:"" help:"Serve the API and frontend, and run the background jobs."`
+	Verify commands.VerifyCmd `cmd:"" help:"Check that scanned media files are not missing, truncated, or modified."`
 }
 
 func main() {
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS channel_tombstones;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- The YouTube API does not return anything for channels that have been deleted or terminated, so we keep track of
+-- the channels we asked for that were not returned
+CREATE TABLE IF NOT EXISTS channel_tombstones (
+    id INTEGER PRIMARY KEY,
+    youtube_id TEXT NOT NULL,
+    channel_id INTEGER,
+    first_missing_at INTEGER NOT NULL,
+    last_checked_at INTEGER NOT NULL,
+    last_seen_at INTEGER,
+    FOREIGN KEY(channel_id) REFERENCES channels(id) ON DELETE SET NULL,
+    UNIQUE(youtube_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE channels ADD COLUMN last_seen_at INTEGER;
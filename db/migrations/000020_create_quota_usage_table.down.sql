@@ -0,0 +1 @@
+DROP TABLE IF EXISTS quota_usage;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- YouTube Data API quota usage per day. The quota resets at midnight Pacific Time, so day is a date in that timezone.
+-- see: https://developers.google.com/youtube/v3/getting-started#quota
+CREATE TABLE IF NOT EXISTS quota_usage (
+    id INTEGER PRIMARY KEY,
+    day TEXT NOT NULL,
+    method TEXT NOT NULL,
+    calls INTEGER NOT NULL DEFAULT 0,
+    units INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(day, method)
+);
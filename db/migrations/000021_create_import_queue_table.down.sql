@@ -0,0 +1 @@
+DROP TABLE IF EXISTS import_queue;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- Items that still need to be imported. Anything left over when an import stops early is picked up by the next run.
+CREATE TABLE IF NOT EXISTS import_queue (
+    id INTEGER PRIMARY KEY,
+    kind TEXT NOT NULL,
+    item TEXT NOT NULL,
+    queued_at INTEGER NOT NULL,
+    UNIQUE(kind, item) ON CONFLICT IGNORE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE channel_thumbnails DROP COLUMN local_path;
+ALTER TABLE channel_banners DROP COLUMN local_path;
+ALTER TABLE video_thumbnails DROP COLUMN local_path;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- Path of the downloaded image, relative to the media directory. NULL until the image has been downloaded.
+ALTER TABLE channel_thumbnails ADD COLUMN local_path TEXT;
+ALTER TABLE channel_banners ADD COLUMN local_path TEXT;
+ALTER TABLE video_thumbnails ADD COLUMN local_path TEXT;
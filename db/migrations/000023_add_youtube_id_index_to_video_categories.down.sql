@@ -0,0 +1 @@
+DROP INDEX IF EXISTS video_categories_youtube_id;
\ No newline at end of file
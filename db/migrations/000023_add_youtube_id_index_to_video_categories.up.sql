@@ -0,0 +1,2 @@
+-- Categories only known by their title (ex. from yt-dlp) do not have a YouTube ID yet
+CREATE UNIQUE INDEX IF NOT EXISTS video_categories_youtube_id ON video_categories(youtube_id) WHERE youtube_id != '';
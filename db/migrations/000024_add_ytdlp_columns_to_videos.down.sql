@@ -0,0 +1,19 @@
+ALTER TABLE videos DROP COLUMN asr;
+ALTER TABLE videos DROP COLUMN abr;
+ALTER TABLE videos DROP COLUMN audio_codec;
+ALTER TABLE videos DROP COLUMN vbr;
+ALTER TABLE videos DROP COLUMN video_codec;
+ALTER TABLE videos DROP COLUMN dynamic_range;
+ALTER TABLE videos DROP COLUMN tbr;
+ALTER TABLE videos DROP COLUMN file_size;
+ALTER TABLE videos DROP COLUMN ext;
+ALTER TABLE videos DROP COLUMN format_note;
+ALTER TABLE videos DROP COLUMN format_id;
+ALTER TABLE videos DROP COLUMN format;
+ALTER TABLE videos DROP COLUMN aspect_ratio;
+ALTER TABLE videos DROP COLUMN resolution;
+ALTER TABLE videos DROP COLUMN height;
+ALTER TABLE videos DROP COLUMN width;
+ALTER TABLE videos DROP COLUMN epoch;
+ALTER TABLE videos DROP COLUMN uploaded_at;
+ALTER TABLE videos DROP COLUMN full_title;
\ No newline at end of file
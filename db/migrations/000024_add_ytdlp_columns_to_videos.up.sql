@@ -0,0 +1,20 @@
+-- Fields from the yt-dlp info.json that are not available from the YouTube Data API
+ALTER TABLE videos ADD COLUMN full_title TEXT;
+ALTER TABLE videos ADD COLUMN uploaded_at INTEGER;
+ALTER TABLE videos ADD COLUMN epoch INTEGER;
+ALTER TABLE videos ADD COLUMN width INTEGER;
+ALTER TABLE videos ADD COLUMN height INTEGER;
+ALTER TABLE videos ADD COLUMN resolution TEXT;
+ALTER TABLE videos ADD COLUMN aspect_ratio DECIMAL;
+ALTER TABLE videos ADD COLUMN format TEXT;
+ALTER TABLE videos ADD COLUMN format_id TEXT;
+ALTER TABLE videos ADD COLUMN format_note TEXT;
+ALTER TABLE videos ADD COLUMN ext TEXT;
+ALTER TABLE videos ADD COLUMN file_size INTEGER;
+ALTER TABLE videos ADD COLUMN tbr DECIMAL;
+ALTER TABLE videos ADD COLUMN dynamic_range TEXT;
+ALTER TABLE videos ADD COLUMN video_codec TEXT;
+ALTER TABLE videos ADD COLUMN vbr DECIMAL;
+ALTER TABLE videos ADD COLUMN audio_codec TEXT;
+ALTER TABLE videos ADD COLUMN abr DECIMAL;
+ALTER TABLE videos ADD COLUMN asr INTEGER;
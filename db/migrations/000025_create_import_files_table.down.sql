@@ -0,0 +1 @@
+DROP TABLE IF EXISTS import_files;
\ No newline at end of file
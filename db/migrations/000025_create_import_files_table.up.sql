@@ -0,0 +1,10 @@
+-- JSON files that have already been imported. A file is only imported again when its contents change.
+CREATE TABLE IF NOT EXISTS import_files (
+    id INTEGER PRIMARY KEY,
+    path TEXT NOT NULL,
+    hash TEXT NOT NULL, -- SHA-256 of the file contents
+    video_id INTEGER, -- NULL for JSON files that are not a video
+    imported_at INTEGER NOT NULL,
+    FOREIGN KEY(video_id) REFERENCES videos(id) ON DELETE CASCADE,
+    UNIQUE(path)
+);
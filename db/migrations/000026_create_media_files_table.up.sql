@@ -0,0 +1,17 @@
+-- Media files found next to yt-dlp info.json files. The size, modification time, and checksum are from when the file
+-- was scanned, so later changes to the file can be found.
+CREATE TABLE IF NOT EXISTS media_files (
+    id INTEGER PRIMARY KEY,
+    video_id INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    modified_at INTEGER NOT NULL,
+    checksum TEXT NOT NULL, -- SHA-256 of the file contents
+    scanned_at INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'ok', -- ok, missing, truncated, or modified as of the last verify
+    verified_at INTEGER,
+    FOREIGN KEY(video_id) REFERENCES videos(id) ON DELETE CASCADE,
+    UNIQUE(path)
+);
+
+CREATE INDEX IF NOT EXISTS media_files_video_id ON media_files(video_id);
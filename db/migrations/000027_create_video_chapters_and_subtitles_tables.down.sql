@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS video_subtitles;
+DROP TABLE IF EXISTS video_chapters;
\ No newline at end of file
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS video_chapters (
+    id INTEGER PRIMARY KEY,
+    video_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    start_time REAL NOT NULL, -- seconds from the start of the video
+    end_time REAL NOT NULL,
+    title TEXT NOT NULL,
+    FOREIGN KEY(video_id) REFERENCES videos(id) ON DELETE CASCADE,
+    UNIQUE(video_id, position)
+);
+
+-- Subtitle and automatic caption languages a video has, with the file formats each one is available in
+CREATE TABLE IF NOT EXISTS video_subtitles (
+    id INTEGER PRIMARY KEY,
+    video_id INTEGER NOT NULL,
+    language TEXT NOT NULL,
+    name TEXT,
+    formats TEXT NOT NULL, -- comma separated file extensions (ex. vtt,srv3,json3)
+    is_automatic BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY(video_id) REFERENCES videos(id) ON DELETE CASCADE,
+    UNIQUE(video_id, language, is_automatic)
+);
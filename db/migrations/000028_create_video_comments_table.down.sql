@@ -0,0 +1 @@
+DROP TABLE IF EXISTS video_comments;
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- Comments saved by yt-dlp --write-comments. Replies have the YouTube ID of the comment they reply to.
+CREATE TABLE IF NOT EXISTS video_comments (
+    id INTEGER PRIMARY KEY,
+    video_id INTEGER NOT NULL,
+    youtube_id TEXT NOT NULL,
+    parent_youtube_id TEXT, -- NULL for top level comments
+    position INTEGER NOT NULL, -- order yt-dlp saved the comments in
+    text TEXT,
+    author TEXT,
+    author_id TEXT,
+    author_thumbnail TEXT,
+    author_is_uploader BOOLEAN,
+    author_is_verified BOOLEAN,
+    like_count INTEGER,
+    is_favorited BOOLEAN,
+    is_pinned BOOLEAN,
+    published_at INTEGER,
+    FOREIGN KEY(video_id) REFERENCES videos(id) ON DELETE CASCADE,
+    UNIQUE(video_id, youtube_id) ON CONFLICT IGNORE
+);
+
+CREATE INDEX IF NOT EXISTS video_comments_parent_youtube_id ON video_comments(video_id, parent_youtube_id);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS playlists (
+    id INTEGER PRIMARY KEY,
+    youtube_id TEXT NOT NULL,
+    channel_id INTEGER, -- NULL when the channel has not been imported
+    title TEXT,
+    description TEXT,
+    webpage_url TEXT,
+    availability TEXT,
+    view_count INTEGER,
+    modified_at INTEGER,
+    FOREIGN KEY(channel_id) REFERENCES channels(id) ON DELETE SET NULL,
+    UNIQUE(youtube_id)
+);
+
+-- Videos are stored by YouTube ID, so playlists can list videos that have not been imported
+CREATE TABLE IF NOT EXISTS playlist_videos (
+    id INTEGER PRIMARY KEY,
+    playlist_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    video_youtube_id TEXT NOT NULL,
+    title TEXT,
+    FOREIGN KEY(playlist_id) REFERENCES playlists(id) ON DELETE CASCADE,
+    UNIQUE(playlist_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS playlist_videos_video_youtube_id ON playlist_videos(video_youtube_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS search_history;
+DROP TABLE IF EXISTS watch_history;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- Watch and search history from Google Takeout. Videos are stored by YouTube ID, so history can include videos that
+-- have not been imported.
+CREATE TABLE IF NOT EXISTS watch_history (
+    id INTEGER PRIMARY KEY,
+    video_youtube_id TEXT NOT NULL,
+    title TEXT,
+    channel_youtube_id TEXT,
+    channel_title TEXT,
+    watched_at INTEGER NOT NULL,
+    UNIQUE(video_youtube_id, watched_at) ON CONFLICT IGNORE
+);
+
+CREATE INDEX IF NOT EXISTS watch_history_watched_at ON watch_history(watched_at);
+
+CREATE TABLE IF NOT EXISTS search_history (
+    id INTEGER PRIMARY KEY,
+    query TEXT NOT NULL,
+    searched_at INTEGER NOT NULL,
+    UNIQUE(query, searched_at) ON CONFLICT IGNORE
+);
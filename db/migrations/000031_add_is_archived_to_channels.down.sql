@@ -0,0 +1 @@
+ALTER TABLE channels DROP COLUMN is_archived;
\ No newline at end of file
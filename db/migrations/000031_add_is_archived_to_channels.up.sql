@@ -0,0 +1,2 @@
+-- the API has always returned is_archived for channels, but the column was never added
+ALTER TABLE channels ADD COLUMN is_archived BOOLEAN DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_groups_channels;
+DROP TABLE IF EXISTS channel_groups;
\ No newline at end of file
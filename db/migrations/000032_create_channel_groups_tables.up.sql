@@ -0,0 +1,15 @@
+-- Groups of channels, used to share or export curated lists of channels
+CREATE TABLE IF NOT EXISTS channel_groups (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    UNIQUE(name) ON CONFLICT IGNORE
+);
+
+CREATE TABLE IF NOT EXISTS channel_groups_channels (
+    id INTEGER PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    channel_id INTEGER NOT NULL,
+    FOREIGN KEY(group_id) REFERENCES channel_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY(channel_id) REFERENCES channels(id) ON DELETE CASCADE,
+    UNIQUE(group_id, channel_id) ON CONFLICT IGNORE
+);
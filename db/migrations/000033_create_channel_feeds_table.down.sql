@@ -0,0 +1 @@
+DROP TABLE IF EXISTS channel_feeds;
\ No newline at end of file
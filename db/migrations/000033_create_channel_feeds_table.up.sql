@@ -0,0 +1,12 @@
+-- The last time each channel's RSS feed was checked. The ETag and Last-Modified headers are sent back on the next
+-- check, so feeds that have not changed are not downloaded again.
+CREATE TABLE IF NOT EXISTS channel_feeds (
+    id INTEGER PRIMARY KEY,
+    channel_id INTEGER NOT NULL,
+    etag TEXT,
+    last_modified TEXT,
+    status INTEGER NOT NULL, -- HTTP status of the last check
+    checked_at INTEGER NOT NULL,
+    FOREIGN KEY(channel_id) REFERENCES channels(id) ON DELETE CASCADE,
+    UNIQUE(channel_id)
+);
@@ -0,0 +1,10 @@
+-- Background jobs run by the server. Run times are kept so a restarted server picks up the schedule where it left off.
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    last_started_at INTEGER,
+    last_finished_at INTEGER,
+    last_error TEXT, -- NULL if the last run succeeded
+    next_run_at INTEGER, -- NULL for jobs that only run when triggered
+    UNIQUE(name)
+);
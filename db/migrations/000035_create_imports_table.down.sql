@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS import_events;
+DROP TABLE IF EXISTS imports;
\ No newline at end of file
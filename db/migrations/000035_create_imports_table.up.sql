@@ -0,0 +1,22 @@
+-- Imports started from the web UI. The output of each import is kept in import_events, so the history can be looked at
+-- after the import has finished.
+CREATE TABLE IF NOT EXISTS imports (
+    id INTEGER PRIMARY KEY,
+    type TEXT NOT NULL, -- subscriptions, takeout, or ytdlp
+    source TEXT, -- uploaded file name or directory
+    status TEXT NOT NULL, -- running, finished, failed, or interrupted if the server stopped before it finished
+    error TEXT,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    started_at INTEGER NOT NULL,
+    finished_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS import_events (
+    id INTEGER PRIMARY KEY,
+    import_id INTEGER NOT NULL,
+    seq INTEGER NOT NULL, -- sent as the event ID, so reconnecting clients can pick up where they left off
+    type TEXT NOT NULL, -- message or error
+    message TEXT NOT NULL,
+    FOREIGN KEY(import_id) REFERENCES imports(id) ON DELETE CASCADE,
+    UNIQUE(import_id, seq)
+);
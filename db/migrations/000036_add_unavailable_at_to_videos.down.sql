@@ -0,0 +1 @@
+ALTER TABLE videos DROP COLUMN unavailable_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- When the YouTube API stopped returning the video, because it was made private or deleted. NULL while it is available.
+ALTER TABLE videos ADD COLUMN unavailable_at INTEGER;
@@ -0,0 +1,34 @@
+-- Notifications sent by the server when something happens to a subscription, see the notify package
+CREATE TABLE IF NOT EXISTS notification_rules (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    event TEXT NOT NULL, -- new_upload, channel_terminated, or archived_video_unavailable
+    group_id INTEGER, -- only channels in the group, or every channel if NULL
+    sink TEXT NOT NULL, -- webhook, ntfy, or email
+    target TEXT NOT NULL, -- URL the notification is sent to
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY(group_id) REFERENCES channel_groups(id) ON DELETE CASCADE,
+    UNIQUE(name)
+);
+
+-- Every notification that was sent, or is waiting to be retried
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id INTEGER PRIMARY KEY,
+    rule_id INTEGER NOT NULL,
+    channel_id INTEGER,
+    video_id INTEGER,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    url TEXT,
+    status TEXT NOT NULL, -- pending, delivered, or failed once it runs out of retries
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at INTEGER NOT NULL,
+    next_attempt_at INTEGER, -- NULL once delivered or failed
+    delivered_at INTEGER,
+    FOREIGN KEY(rule_id) REFERENCES notification_rules(id) ON DELETE CASCADE,
+    FOREIGN KEY(channel_id) REFERENCES channels(id) ON DELETE SET NULL,
+    FOREIGN KEY(video_id) REFERENCES videos(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS notification_deliveries_status ON notification_deliveries(status, next_attempt_at);
@@ -0,0 +1,6 @@
+ALTER TABLE channel_thumbnails DROP COLUMN download_status;
+ALTER TABLE channel_thumbnails DROP COLUMN download_attempts;
+ALTER TABLE channel_banners DROP COLUMN download_status;
+ALTER TABLE channel_banners DROP COLUMN download_attempts;
+ALTER TABLE video_thumbnails DROP COLUMN download_status;
+ALTER TABLE video_thumbnails DROP COLUMN download_attempts;
\ No newline at end of file
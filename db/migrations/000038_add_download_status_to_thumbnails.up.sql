@@ -0,0 +1,8 @@
+-- How downloading the image went the last time it failed. Images whose URL is gone (404 or 410) are not downloaded
+-- again until the URL changes.
+ALTER TABLE channel_thumbnails ADD COLUMN download_status INTEGER;
+ALTER TABLE channel_thumbnails ADD COLUMN download_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE channel_banners ADD COLUMN download_status INTEGER;
+ALTER TABLE channel_banners ADD COLUMN download_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE video_thumbnails ADD COLUMN download_status INTEGER;
+ALTER TABLE video_thumbnails ADD COLUMN download_attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE channel_feeds DROP COLUMN downloaded_at;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- The last time the feed was downloaded or was not modified. NULL until the feed has been seen, so the uploads that are
+-- already in a feed are not reported as new, even when the first checks got an error or a 404.
+ALTER TABLE channel_feeds ADD COLUMN downloaded_at INTEGER;
+UPDATE channel_feeds SET downloaded_at = checked_at WHERE status IN (200, 304);
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	VideosDiscovered    = "videos_discovered"    // new uploads were found in the channel RSS feeds
+	ChannelsRefreshed   = "channels_refreshed"   // channel details and statistics were imported from the API
+	ChannelsDisappeared = "channels_disappeared" // channels were terminated or deleted
+	VideosUnavailable   = "videos_unavailable"   // videos were made private or deleted
+	ImportFinished      = "import_finished"      // an import started from the API finished
+)
+
+// recentEvents is the number of events kept so clients that reconnect do not miss anything.
+const recentEvents = 256
+
+// subscriberBuffer is the number of events a subscriber can fall behind before it is dropped.
+const subscriberBuffer = 64
+
+// Event is a change to the database. It only has the IDs of what changed, which can be requested from the API.
+type Event struct {
+	ID         int64   `json:"id"`
+	Type       string  `json:"type"`
+	Time       int64   `json:"time"` // unix timestamp
+	ChannelIDs []int64 `json:"channel_ids,omitempty"`
+	VideoIDs   []int64 `json:"video_ids,omitempty"`
+	ImportID   int64   `json:"import_id,omitempty"`
+}
+
+// Bus sends events to every subscriber. Publishing never blocks, subscribers that fall too far behind are dropped and
+// can subscribe again from the last event they got.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      int64
+	recent      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus creates a bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[chan Event]struct{})}
+}
+
+// Publish sends an event to the subscribers. The ID and time are filled in.
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time == 0 {
+		e.Time = time.Now().Unix()
+	}
+
+	b.recent = append(b.recent, e)
+	if len(b.recent) > recentEvents {
+		b.recent = b.recent[len(b.recent)-recentEvents:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe gets the events published after the event with the given ID, which is 0 for only new events. Events that
+// are too old to be kept are skipped. The channel is closed if the subscriber falls behind, or once unsubscribe is
+// called.
+func (b *Bus) Subscribe(after int64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer+recentEvents)
+	if after > 0 {
+		for _, e := range b.recent {
+			if e.ID > after {
+				ch <- e
+			}
+		}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+type busKey struct{}
+
+// NewContext returns a context that publishes events to b.
+func NewContext(ctx context.Context, b *Bus) context.Context {
+	return context.WithValue(ctx, busKey{}, b)
+}
+
+// Publish sends an event to the bus in the context. Nothing happens if there is no bus, like when importing from the
+// command line.
+func Publish(ctx context.Context, e Event) {
+	if b, ok := ctx.Value(busKey{}).(*Bus); ok {
+		b.Publish(e)
+	}
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+// drain reads the events that are waiting on ch, and whether ch was closed.
+func drain(ch <-chan Event) ([]Event, bool) {
+	var received []Event
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return received, true
+			}
+			received = append(received, e)
+		default:
+			return received, false
+		}
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+
+	slow, unsubscribeSlow := bus.Subscribe(0)
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := bus.Subscribe(0)
+	defer unsubscribeFast()
+
+	var fastReceived []Event
+	published := subscriberBuffer + recentEvents + 1
+	for i := range published {
+		bus.Publish(Event{Type: VideosDiscovered, VideoIDs: []int64{int64(i + 1)}})
+
+		received, closed := drain(fast)
+		if closed {
+			t.Fatalf("subscriber that keeps up was dropped after %d events", i+1)
+		}
+		fastReceived = append(fastReceived, received...)
+	}
+
+	if len(fastReceived) != published {
+		t.Errorf("subscriber that keeps up got %d events, want %d", len(fastReceived), published)
+	}
+
+	received, closed := drain(slow)
+	if !closed {
+		t.Fatal("slow subscriber was not dropped")
+	}
+	if len(received) != published-1 {
+		t.Fatalf("slow subscriber got %d events before it was dropped, want %d", len(received), published-1)
+	}
+
+	// reconnecting from the last event it got only sends what it missed
+	last := received[len(received)-1].ID
+	missed, unsubscribe := bus.Subscribe(last)
+	defer unsubscribe()
+
+	replayed, _ := drain(missed)
+	if len(replayed) != 1 || replayed[0].ID != last+1 || replayed[0].VideoIDs[0] != int64(published) {
+		t.Errorf("events after %d: %+v, want only event %d", last, replayed, last+1)
+	}
+}
+
+func TestBusReplay(t *testing.T) {
+	bus := NewBus()
+
+	published := recentEvents + 10
+	for range published {
+		bus.Publish(Event{Type: ChannelsRefreshed, ChannelIDs: []int64{1}})
+	}
+
+	tests := []struct {
+		name      string
+		after     int64
+		wantFirst int64
+		wantCount int
+	}{
+		{"new events only", 0, 0, 0},
+		{"recent events", 200, 201, published - 200},
+		{"older events are skipped", 5, int64(published - recentEvents + 1), recentEvents},
+		{"up to date", int64(published), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, unsubscribe := bus.Subscribe(tt.after)
+			defer unsubscribe()
+
+			received, _ := drain(ch)
+			if len(received) != tt.wantCount {
+				t.Fatalf("got %d events, want %d", len(received), tt.wantCount)
+			}
+			if tt.wantCount > 0 && received[0].ID != tt.wantFirst {
+				t.Errorf("first event %d, want %d", received[0].ID, tt.wantFirst)
+			}
+		})
+	}
+
+	// unsubscribing closes the channel, and calling it again does nothing
+	ch, unsubscribe := bus.Subscribe(0)
+	unsubscribe()
+	unsubscribe()
+	if _, closed := drain(ch); !closed {
+		t.Error("channel is still open after unsubscribing")
+	}
+}
+
+func TestPublishWithoutBus(t *testing.T) {
+	// imports run from the command line have no bus
+	Publish(context.Background(), Event{Type: ImportFinished, ImportID: 1})
+
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	Publish(NewContext(context.Background(), bus), Event{Type: ImportFinished, ImportID: 1})
+	received, _ := drain(ch)
+	if len(received) != 1 || received[0].ImportID != 1 || received[0].Time == 0 {
+		t.Errorf("published %+v, want the import_finished event with a time", received)
+	}
+}
@@ -0,0 +1,994 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// backup streams, in the order they are imported
+const (
+	BackupTopics          = "topics"
+	BackupCategories      = "categories"
+	BackupChannels        = "channels"
+	BackupMissingChannels = "missing_channels"
+	BackupVideos          = "videos"
+	BackupTags            = "tags"
+	BackupUserData        = "user_data"
+)
+
+// BackupStreams are the JSON Lines files that make up a backup.
+var BackupStreams = []string{
+	BackupTopics,
+	BackupCategories,
+	BackupChannels,
+	BackupMissingChannels,
+	BackupVideos,
+	BackupTags,
+	BackupUserData,
+}
+
+// backupRecord is a line in a backup stream. Rows are stored by column name instead of by position, and other rows are
+// referred to by their YouTube ID or other natural key instead of the database ID. This keeps backups independent of
+// the schema version: columns the database does not have are skipped, and columns the backup does not have get their
+// default value.
+type backupRecord map[string]any
+
+// backupChildren are rows that belong to a single parent row, like a video's thumbnails. They are stored as a list in
+// the parent's record, and replaced when the parent is imported.
+type backupChildren struct {
+	key      string // field in the parent record
+	table    string
+	parent   string // column with the parent row ID
+	order    string
+	conflict string // conflict target for children that are unique across parents
+}
+
+var channelBackupChildren = []backupChildren{
+	{key: "thumbnails", table: "channel_thumbnails", parent: "channel_id", order: "size"},
+	{key: "banners", table: "channel_banners", parent: "channel_id", order: "id"},
+}
+
+var videoBackupChildren = []backupChildren{
+	{key: "thumbnails", table: "video_thumbnails", parent: "video_id", order: "size"},
+	{key: "archived_thumbnails", table: "archived_video_thumbnails", parent: "video_id", order: "id"},
+	{key: "formats", table: "archived_video_formats", parent: "video_id", order: "id"},
+	{key: "chapters", table: "video_chapters", parent: "video_id", order: "position"},
+	{key: "subtitles", table: "video_subtitles", parent: "video_id", order: "is_automatic, language"},
+	{key: "comments", table: "video_comments", parent: "video_id", order: "position"},
+	{key: "media_files", table: "media_files", parent: "video_id", order: "path", conflict: "(path)"},
+}
+
+var playlistBackupChildren = []backupChildren{
+	{key: "videos", table: "playlist_videos", parent: "playlist_id", order: "position"},
+}
+
+// backupWriter creates the files of a backup, either in a directory or a zip file. Only the last file created can be
+// written to.
+type backupWriter interface {
+	Create(name string) (io.Writer, error)
+	Close() error
+}
+
+type dirBackupWriter struct {
+	dir  string
+	file *os.File
+}
+
+func (w *dirBackupWriter) Create(name string) (io.Writer, error) {
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	w.file = f
+
+	return f, nil
+}
+
+func (w *dirBackupWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+// WriteBackup exports the whole database as JSON Lines, one file per stream. The backup is written to a zip file if
+// dest ends in .zip, and to a directory otherwise. Everything is read in a single transaction, so the backup is
+// consistent even if an import is running.
+func WriteBackup(ctx context.Context, db *sql.DB, dest string) error {
+	var bw backupWriter
+	if strings.HasSuffix(strings.ToLower(dest), ".zip") {
+		f, err := os.Create(dest)
+		if err != nil {
+			return fmt.Errorf("could not create backup: file = \"%s\": %w", dest, err)
+		}
+		defer f.Close()
+
+		bw = zip.NewWriter(f)
+	} else {
+		err := os.MkdirAll(dest, 0755)
+		if err != nil {
+			return fmt.Errorf("could not create backup: dir = \"%s\": %w", dest, err)
+		}
+
+		bw = &dirBackupWriter{dir: dest}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exporters := map[string]func(context.Context, DBTX, func(backupRecord) error) error{
+		BackupTopics:          exportBackupTopics,
+		BackupCategories:      exportBackupCategories,
+		BackupChannels:        exportBackupChannels,
+		BackupMissingChannels: exportBackupMissingChannels,
+		BackupVideos:          exportBackupVideos,
+		BackupTags:            exportBackupTags,
+		BackupUserData:        exportBackupUserData,
+	}
+
+	for _, stream := range BackupStreams {
+		out, err := bw.Create(stream + ".jsonl")
+		if err != nil {
+			return fmt.Errorf("could not create %s stream: %w", stream, err)
+		}
+
+		buf := bufio.NewWriter(out)
+		enc := json.NewEncoder(buf)
+
+		count := 0
+		err = exporters[stream](ctx, tx, func(r backupRecord) error {
+			count++
+			return enc.Encode(r)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to export %s: %w", stream, err)
+		}
+
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("exported %d %s\n", count, strings.ReplaceAll(stream, "_", " "))
+	}
+
+	return bw.Close()
+}
+
+func exportBackupTopics(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	err := queryBackupRecords(ctx, db, "SELECT * FROM channel_topics ORDER BY topic_id", nil, func(r backupRecord) error {
+		delete(r, "id")
+		r["kind"] = "channel"
+		return write(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	return queryBackupRecords(ctx, db, "SELECT * FROM video_topics ORDER BY url", nil, func(r backupRecord) error {
+		delete(r, "id")
+		r["kind"] = "video"
+		return write(r)
+	})
+}
+
+func exportBackupCategories(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	return queryBackupRecords(ctx, db, "SELECT * FROM video_categories ORDER BY youtube_id, title", nil, func(r backupRecord) error {
+		delete(r, "id")
+		return write(r)
+	})
+}
+
+func exportBackupChannels(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	return queryBackupRecords(ctx, db, "SELECT * FROM channels ORDER BY youtube_id", nil, func(r backupRecord) error {
+		id := r["id"]
+		delete(r, "id")
+
+		err := exportBackupChildren(ctx, db, r, id, channelBackupChildren)
+		if err != nil {
+			return err
+		}
+
+		err = exportBackupStrings(ctx, db, r, "keywords", `SELECT keywords.keyword FROM channels_channel_keywords
+			JOIN keywords ON keywords.id = channels_channel_keywords.keyword_id
+			WHERE channels_channel_keywords.channel_id = ? ORDER BY keywords.keyword`, id)
+		if err != nil {
+			return err
+		}
+
+		err = exportBackupStrings(ctx, db, r, "topics", `SELECT channel_topics.topic_id FROM channels_channel_topics
+			JOIN channel_topics ON channel_topics.id = channels_channel_topics.topic_id
+			WHERE channels_channel_topics.channel_id = ? ORDER BY channel_topics.topic_id`, id)
+		if err != nil {
+			return err
+		}
+
+		return write(r)
+	})
+}
+
+func exportBackupMissingChannels(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	return queryBackupRecords(ctx, db, "SELECT * FROM channel_tombstones ORDER BY youtube_id", nil, func(r backupRecord) error {
+		// the channel is found again by its YouTube ID
+		delete(r, "id")
+		delete(r, "channel_id")
+		return write(r)
+	})
+}
+
+func exportBackupVideos(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	query := `SELECT videos.*, channels.youtube_id AS channel_youtube_id FROM videos
+		LEFT JOIN channels ON channels.id = videos.channel_id
+		ORDER BY videos.youtube_id`
+
+	return queryBackupRecords(ctx, db, query, nil, func(r backupRecord) error {
+		id := r["id"]
+		delete(r, "id")
+		delete(r, "channel_id")
+
+		err := exportBackupChildren(ctx, db, r, id, videoBackupChildren)
+		if err != nil {
+			return err
+		}
+
+		categories, err := queryBackupList(ctx, db, `SELECT video_categories.youtube_id, video_categories.title FROM videos_video_categories
+			JOIN video_categories ON video_categories.id = videos_video_categories.category_id
+			WHERE videos_video_categories.video_id = ? ORDER BY video_categories.youtube_id, video_categories.title`, []any{id})
+		if err != nil {
+			return err
+		}
+		if len(categories) > 0 {
+			r["categories"] = categories
+		}
+
+		err = exportBackupStrings(ctx, db, r, "topics", `SELECT video_topics.url FROM videos_video_topics
+			JOIN video_topics ON video_topics.id = videos_video_topics.topic_id
+			WHERE videos_video_topics.video_id = ? ORDER BY video_topics.url`, id)
+		if err != nil {
+			return err
+		}
+
+		return write(r)
+	})
+}
+
+// exportBackupTags writes one record per tag with the videos that use it.
+func exportBackupTags(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	query := `SELECT video_tags.tag, videos.youtube_id FROM videos_video_tags
+		JOIN video_tags ON video_tags.id = videos_video_tags.tag_id
+		JOIN videos ON videos.id = videos_video_tags.video_id
+		ORDER BY video_tags.tag, videos.youtube_id`
+
+	var current backupRecord
+	err := queryBackupRecords(ctx, db, query, nil, func(r backupRecord) error {
+		if current != nil && current["tag"] != r["tag"] {
+			if err := write(current); err != nil {
+				return err
+			}
+			current = nil
+		}
+
+		if current == nil {
+			current = backupRecord{"tag": r["tag"], "videos": []any{}}
+		}
+		current["videos"] = append(current["videos"].([]any), r["youtube_id"])
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		return write(current)
+	}
+
+	return nil
+}
+
+// exportBackupUserData writes the data that came from the user instead of YouTube: channel groups, notification rules,
+// playlists, and history. Records are told apart by their kind.
+func exportBackupUserData(ctx context.Context, db DBTX, write func(backupRecord) error) error {
+	err := queryBackupRecords(ctx, db, "SELECT * FROM channel_groups ORDER BY name", nil, func(r backupRecord) error {
+		id := r["id"]
+		delete(r, "id")
+		r["kind"] = "group"
+
+		err := exportBackupStrings(ctx, db, r, "channels", `SELECT channels.youtube_id FROM channel_groups_channels
+			JOIN channels ON channels.id = channel_groups_channels.channel_id
+			WHERE channel_groups_channels.group_id = ? ORDER BY channels.youtube_id`, id)
+		if err != nil {
+			return err
+		}
+
+		return write(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	// the delivery log is left out, it is only a record of what was sent
+	query := `SELECT notification_rules.*, channel_groups.name AS group_name FROM notification_rules
+		LEFT JOIN channel_groups ON channel_groups.id = notification_rules.group_id
+		ORDER BY notification_rules.name`
+	err = queryBackupRecords(ctx, db, query, nil, func(r backupRecord) error {
+		delete(r, "id")
+		delete(r, "group_id")
+		r["kind"] = "rule"
+		return write(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	query = `SELECT playlists.*, channels.youtube_id AS channel_youtube_id FROM playlists
+		LEFT JOIN channels ON channels.id = playlists.channel_id
+		ORDER BY playlists.youtube_id`
+	err = queryBackupRecords(ctx, db, query, nil, func(r backupRecord) error {
+		id := r["id"]
+		delete(r, "id")
+		delete(r, "channel_id")
+		r["kind"] = "playlist"
+
+		err := exportBackupChildren(ctx, db, r, id, playlistBackupChildren)
+		if err != nil {
+			return err
+		}
+
+		return write(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = queryBackupRecords(ctx, db, "SELECT * FROM watch_history ORDER BY watched_at, video_youtube_id", nil, func(r backupRecord) error {
+		delete(r, "id")
+		r["kind"] = "watch"
+		return write(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	return queryBackupRecords(ctx, db, "SELECT * FROM search_history ORDER BY searched_at, query", nil, func(r backupRecord) error {
+		delete(r, "id")
+		r["kind"] = "search"
+		return write(r)
+	})
+}
+
+// queryBackupRecords calls fn with every row of the query. Rows are read by column name, so new columns are exported
+// without having to change the query.
+func queryBackupRecords(ctx context.Context, db DBTX, query string, args []any, fn func(backupRecord) error) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		r := backupRecord{}
+		for i, column := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				r[column] = string(v)
+			default:
+				r[column] = v
+			}
+		}
+
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return err
+	}
+
+	return rows.Err()
+}
+
+func queryBackupList(ctx context.Context, db DBTX, query string, args []any, omit ...string) ([]backupRecord, error) {
+	var list []backupRecord
+	err := queryBackupRecords(ctx, db, query, args, func(r backupRecord) error {
+		for _, column := range omit {
+			delete(r, column)
+		}
+		list = append(list, r)
+		return nil
+	})
+
+	return list, err
+}
+
+func exportBackupChildren(ctx context.Context, db DBTX, r backupRecord, id any, children []backupChildren) error {
+	for _, c := range children {
+		query := fmt.Sprintf("SELECT * FROM %s WHERE %s = ? ORDER BY %s", c.table, c.parent, c.order)
+		list, err := queryBackupList(ctx, db, query, []any{id}, "id", c.parent)
+		if err != nil {
+			return fmt.Errorf("could not export %s: %w", c.table, err)
+		}
+
+		if len(list) > 0 {
+			r[c.key] = list
+		}
+	}
+
+	return nil
+}
+
+// exportBackupStrings adds the first column of every row of the query as a list.
+func exportBackupStrings(ctx context.Context, db DBTX, r backupRecord, key string, query string, args ...any) error {
+	var list []any
+	err := queryBackupRecords(ctx, db, query, args, func(row backupRecord) error {
+		for _, v := range row {
+			list = append(list, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(list) > 0 {
+		r[key] = list
+	}
+
+	return nil
+}
+
+// BackupImporter restores a backup written by WriteBackup. Rows are matched by their YouTube ID, or other natural key,
+// so a backup can be imported into a database that already has data. Existing rows are updated, and the rows that
+// belong to them, like thumbnails and comments, are replaced.
+type BackupImporter struct {
+	db *sql.DB
+}
+
+// NewBackupImporter creates a backup importer.
+func NewBackupImporter(db *sql.DB) *BackupImporter {
+	return &BackupImporter{db: db}
+}
+
+// backupTx imports records into a transaction. The columns of each table are looked up once and only those columns are
+// imported.
+type backupTx struct {
+	tx      *sql.Tx
+	columns map[string][]string
+}
+
+// Import restores a backup from a directory or zip file. Streams that are missing from the backup are skipped.
+// Everything is imported in a single transaction, so a backup that fails to import does not leave partial data behind.
+func (bi *BackupImporter) Import(ctx context.Context, src string) error {
+	open := func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(src, name))
+	}
+
+	if strings.HasSuffix(strings.ToLower(src), ".zip") {
+		r, err := zip.OpenReader(src)
+		if err != nil {
+			return fmt.Errorf("could not open backup: file = \"%s\": %w", src, err)
+		}
+		defer r.Close()
+
+		open = func(name string) (io.ReadCloser, error) {
+			return r.Open(name)
+		}
+	}
+
+	tx, err := bi.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bt := &backupTx{tx: tx, columns: make(map[string][]string)}
+	importers := map[string]func(context.Context, backupRecord) error{
+		BackupTopics:          bt.importTopic,
+		BackupCategories:      bt.importCategory,
+		BackupChannels:        bt.importChannel,
+		BackupMissingChannels: bt.importMissingChannel,
+		BackupVideos:          bt.importVideo,
+		BackupTags:            bt.importTag,
+		BackupUserData:        bt.importUserData,
+	}
+
+	for _, stream := range BackupStreams {
+		f, err := open(stream + ".jsonl")
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("%s not in backup, skipping\n", stream)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not open %s stream: %w", stream, err)
+		}
+
+		count, err := readBackupStream(ctx, f, importers[stream])
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to import %s: %w", stream, err)
+		}
+		fmt.Printf("imported %d %s\n", count, strings.ReplaceAll(stream, "_", " "))
+	}
+
+	return tx.Commit()
+}
+
+func readBackupStream(ctx context.Context, r io.Reader, fn func(context.Context, backupRecord) error) (int, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	count := 0
+	for {
+		var record backupRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("could not read record %d: %w", count+1, err)
+		}
+
+		if err := fn(ctx, record); err != nil {
+			return count, fmt.Errorf("could not import record %d: %w", count+1, err)
+		}
+		count++
+	}
+}
+
+func (bt *backupTx) importTopic(ctx context.Context, r backupRecord) error {
+	var err error
+	switch r["kind"] {
+	case "channel":
+		_, err = bt.insert(ctx, "channel_topics", r, nil, "(topic_id)")
+	case "video":
+		_, err = bt.insert(ctx, "video_topics", r, nil, "(url)")
+	default:
+		err = fmt.Errorf("unknown topic kind: %v", r["kind"])
+	}
+
+	return err
+}
+
+func (bt *backupTx) importCategory(ctx context.Context, r backupRecord) error {
+	if id, _ := r["youtube_id"].(string); id != "" {
+		_, err := bt.insert(ctx, "video_categories", r, nil, "(youtube_id) WHERE youtube_id != ''")
+		return err
+	}
+
+	// categories from yt-dlp do not have a YouTube ID, so they can only be matched by title
+	_, err := bt.categoryID(ctx, r)
+	return err
+}
+
+func (bt *backupTx) importChannel(ctx context.Context, r backupRecord) error {
+	id, err := bt.insert(ctx, "channels", r, nil, "(youtube_id)")
+	if err != nil {
+		return err
+	}
+
+	err = bt.importChildren(ctx, r, id, channelBackupChildren)
+	if err != nil {
+		return err
+	}
+
+	_, err = bt.tx.ExecContext(ctx, "DELETE FROM channels_channel_keywords WHERE channel_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, keyword := range r.strings("keywords") {
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO keywords(keyword) VALUES(?)", keyword)
+		if err != nil {
+			return fmt.Errorf("could not save keyword: %s : %w", keyword, err)
+		}
+
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO channels_channel_keywords(channel_id, keyword_id) SELECT ?, id FROM keywords WHERE keyword = ?", id, keyword)
+		if err != nil {
+			return fmt.Errorf("could not link keyword: %s : %w", keyword, err)
+		}
+	}
+
+	_, err = bt.tx.ExecContext(ctx, "DELETE FROM channels_channel_topics WHERE channel_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, topic := range r.strings("topics") {
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO channel_topics(topic_id) VALUES(?)", topic)
+		if err != nil {
+			return fmt.Errorf("could not save topic: %s : %w", topic, err)
+		}
+
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO channels_channel_topics(channel_id, topic_id) SELECT ?, id FROM channel_topics WHERE topic_id = ?", id, topic)
+		if err != nil {
+			return fmt.Errorf("could not link topic: %s : %w", topic, err)
+		}
+	}
+
+	return nil
+}
+
+func (bt *backupTx) importMissingChannel(ctx context.Context, r backupRecord) error {
+	channelID, err := bt.channelID(ctx, r["youtube_id"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bt.insert(ctx, "channel_tombstones", r, map[string]any{"channel_id": channelID}, "(youtube_id)")
+	return err
+}
+
+func (bt *backupTx) importVideo(ctx context.Context, r backupRecord) error {
+	channelID, err := bt.channelID(ctx, r["channel_youtube_id"])
+	if err != nil {
+		return err
+	}
+
+	id, err := bt.insert(ctx, "videos", r, map[string]any{"channel_id": channelID}, "(youtube_id)")
+	if err != nil {
+		return err
+	}
+
+	err = bt.importChildren(ctx, r, id, videoBackupChildren)
+	if err != nil {
+		return err
+	}
+
+	_, err = bt.tx.ExecContext(ctx, "DELETE FROM videos_video_categories WHERE video_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, category := range r.records("categories") {
+		categoryID, err := bt.categoryID(ctx, category)
+		if err != nil {
+			return err
+		}
+
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO videos_video_categories(video_id, category_id) VALUES(?, ?)", id, categoryID)
+		if err != nil {
+			return fmt.Errorf("could not link category: %v : %w", category["title"], err)
+		}
+	}
+
+	_, err = bt.tx.ExecContext(ctx, "DELETE FROM videos_video_topics WHERE video_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, topic := range r.strings("topics") {
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO video_topics(url) VALUES(?)", topic)
+		if err != nil {
+			return fmt.Errorf("could not save topic: %s : %w", topic, err)
+		}
+
+		_, err = bt.tx.ExecContext(ctx, "INSERT INTO videos_video_topics(video_id, topic_id) SELECT ?, id FROM video_topics WHERE url = ?", id, topic)
+		if err != nil {
+			return fmt.Errorf("could not link topic: %s : %w", topic, err)
+		}
+	}
+
+	return nil
+}
+
+func (bt *backupTx) importTag(ctx context.Context, r backupRecord) error {
+	tag, _ := r["tag"].(string)
+
+	_, err := bt.tx.ExecContext(ctx, "INSERT INTO video_tags(tag) VALUES(?)", tag)
+	if err != nil {
+		return fmt.Errorf("could not save tag: %s : %w", tag, err)
+	}
+
+	for _, video := range r.strings("videos") {
+		_, err = bt.tx.ExecContext(ctx, `INSERT INTO videos_video_tags(video_id, tag_id)
+			SELECT videos.id, video_tags.id FROM videos, video_tags WHERE videos.youtube_id = ? AND video_tags.tag = ?`, video, tag)
+		if err != nil {
+			return fmt.Errorf("could not link tag: %s : %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+func (bt *backupTx) importUserData(ctx context.Context, r backupRecord) error {
+	switch r["kind"] {
+	case "group":
+		id, err := bt.insert(ctx, "channel_groups", r, nil, "(name)")
+		if err != nil {
+			return err
+		}
+		if id == 0 {
+			// groups are not in this version of the schema
+			return nil
+		}
+
+		for _, channel := range r.strings("channels") {
+			_, err = bt.tx.ExecContext(ctx, "INSERT INTO channel_groups_channels(group_id, channel_id) SELECT ?, id FROM channels WHERE youtube_id = ?", id, channel)
+			if err != nil {
+				return fmt.Errorf("could not add channel to group: %s : %w", channel, err)
+			}
+		}
+	case "rule":
+		groupID, err := bt.groupID(ctx, r["group_name"])
+		if err != nil {
+			return err
+		}
+		if r["group_name"] != nil && groupID == nil {
+			// without its group the rule would send notifications for every channel
+			return fmt.Errorf("could not find group for rule: %v : %v", r["name"], r["group_name"])
+		}
+
+		_, err = bt.insert(ctx, "notification_rules", r, map[string]any{"group_id": groupID}, "(name)")
+		return err
+	case "playlist":
+		channelID, err := bt.channelID(ctx, r["channel_youtube_id"])
+		if err != nil {
+			return err
+		}
+
+		id, err := bt.insert(ctx, "playlists", r, map[string]any{"channel_id": channelID}, "(youtube_id)")
+		if err != nil {
+			return err
+		}
+
+		return bt.importChildren(ctx, r, id, playlistBackupChildren)
+	case "watch":
+		_, err := bt.insert(ctx, "watch_history", r, nil, "")
+		return err
+	case "search":
+		_, err := bt.insert(ctx, "search_history", r, nil, "")
+		return err
+	default:
+		return fmt.Errorf("unknown user data kind: %v", r["kind"])
+	}
+
+	return nil
+}
+
+// importChildren replaces the parent's children with the ones in the record. Tables that do not exist in this version
+// of the schema are skipped.
+func (bt *backupTx) importChildren(ctx context.Context, r backupRecord, parentID int64, children []backupChildren) error {
+	for _, c := range children {
+		columns, err := bt.tableColumns(ctx, c.table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		_, err = bt.tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", c.table, c.parent), parentID)
+		if err != nil {
+			return fmt.Errorf("could not clear %s: %w", c.table, err)
+		}
+
+		for _, child := range r.records(c.key) {
+			_, err = bt.insert(ctx, c.table, child, map[string]any{c.parent: parentID}, c.conflict)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// insert adds a row to the table from the record's columns. set has columns that are not taken from the record, such
+// as parent IDs. If conflict is given, the existing row is updated instead. The ID of the row is returned, or 0 if the
+// row was ignored.
+func (bt *backupTx) insert(ctx context.Context, table string, r backupRecord, set map[string]any, conflict string) (int64, error) {
+	columns, err := bt.tableColumns(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	var names, updates []string
+	var args []any
+	for _, column := range columns {
+		v, ok := set[column]
+		if !ok {
+			v, ok = r[column]
+		}
+		if ok {
+			v, ok = backupValue(v)
+		}
+		if !ok || column == "id" {
+			continue
+		}
+
+		names = append(names, column)
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", column, column))
+		args = append(args, v)
+	}
+
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)", table, strings.Join(names, ", "), strings.Repeat("?, ", len(names)-1)+"?")
+	if conflict != "" {
+		query += fmt.Sprintf(" ON CONFLICT%s DO UPDATE SET %s", conflict, strings.Join(updates, ", "))
+	}
+	query += " RETURNING id"
+
+	var id int64
+	err = bt.tx.QueryRowContext(ctx, query, args...).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// ignored by an ON CONFLICT IGNORE constraint
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("could not save %s: %w", table, err)
+	}
+
+	return id, nil
+}
+
+// tableColumns gets the columns of a table. Tables that do not exist have no columns.
+func (bt *backupTx) tableColumns(ctx context.Context, table string) ([]string, error) {
+	if columns, ok := bt.columns[table]; ok {
+		return columns, nil
+	}
+
+	rows, err := bt.tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, name)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bt.columns[table] = columns
+	return columns, nil
+}
+
+// channelID finds a channel by its YouTube ID. nil is returned for channels that have not been imported, since the
+// channel is optional everywhere it is referenced.
+func (bt *backupTx) channelID(ctx context.Context, youtubeID any) (any, error) {
+	if youtubeID == nil {
+		return nil, nil
+	}
+
+	var id int64
+	err := bt.tx.QueryRowContext(ctx, "SELECT id FROM channels WHERE youtube_id = ?", youtubeID).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// groupID finds a channel group by its name. nil is returned if there is no name, or no group with the name.
+func (bt *backupTx) groupID(ctx context.Context, name any) (any, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	var id int64
+	err := bt.tx.QueryRowContext(ctx, "SELECT id FROM channel_groups WHERE name = ?", name).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// categoryID finds a category by its YouTube ID, or by its title for categories without one. Unknown categories are
+// added.
+func (bt *backupTx) categoryID(ctx context.Context, r backupRecord) (int64, error) {
+	youtubeID, _ := r["youtube_id"].(string)
+	title, _ := r["title"].(string)
+	if youtubeID != "" {
+		return VideoCategoryByYouTubeID(ctx, bt.tx, youtubeID, title)
+	}
+
+	var id int64
+	err := bt.tx.QueryRowContext(ctx, "SELECT id FROM video_categories WHERE youtube_id = '' AND title = ?", title).Scan(&id)
+	switch {
+	case err == nil:
+		return id, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	return bt.insert(ctx, "video_categories", r, map[string]any{"youtube_id": ""}, "")
+}
+
+// backupValue converts a value decoded from JSON to a value that can be saved. Lists and objects are not columns.
+func backupValue(v any) (any, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true
+		}
+		f, err := v.Float64()
+		return f, err == nil
+	case nil, string, bool, int64:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+func (r backupRecord) strings(key string) []string {
+	list, _ := r[key].([]any)
+
+	var values []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+
+	return values
+}
+
+func (r backupRecord) records(key string) []backupRecord {
+	list, _ := r[key].([]any)
+
+	var records []backupRecord
+	for _, v := range list {
+		if m, ok := v.(map[string]any); ok {
+			records = append(records, m)
+		}
+	}
+
+	return records
+}
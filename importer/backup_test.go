@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fillBackupTestDB adds a row to each kind of table that is backed up.
+func fillBackupTestDB(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	channelID := mustExec(t, db, "INSERT INTO channels(youtube_id, title, description, subscriber_count) VALUES(?, ?, ?, ?)",
+		"UC0000000000000000000001", "First", "A channel", 100)
+	mustExec(t, db, "INSERT INTO channel_thumbnails(channel_id, size, width, height, url) VALUES(?, ?, ?, ?, ?)",
+		channelID, "default", 88, 88, "https://yt3.ggpht.com/first")
+	mustExec(t, db, "INSERT INTO channel_banners(channel_id, url) VALUES(?, ?)", channelID, "https://yt3.ggpht.com/banner")
+	keywordID := mustExec(t, db, "INSERT INTO keywords(keyword) VALUES(?)", "cooking")
+	mustExec(t, db, "INSERT INTO channels_channel_keywords(channel_id, keyword_id) VALUES(?, ?)", channelID, keywordID)
+	mustExec(t, db, "INSERT INTO channels_channel_topics(channel_id, topic_id) SELECT ?, id FROM channel_topics WHERE topic_id = '/m/02wbm'", channelID)
+
+	mustExec(t, db, "INSERT INTO channel_tombstones(youtube_id, first_missing_at, last_checked_at) VALUES(?, ?, ?)",
+		"UC0000000000000000000009", 1700000000, 1700000100)
+
+	videoID := mustExec(t, db, "INSERT INTO videos(youtube_id, channel_id, title, duration, uploaded_at, is_archived) VALUES(?, ?, ?, ?, ?, ?)",
+		"video000001", channelID, "First video", 61, 1700000000, true)
+	mustExec(t, db, "INSERT INTO video_thumbnails(video_id, size, width, height, url) VALUES(?, ?, ?, ?, ?)",
+		videoID, "high", 480, 360, "https://i.ytimg.com/vi/video000001/hqdefault.jpg")
+	mustExec(t, db, "INSERT INTO video_chapters(video_id, position, start_time, end_time, title) VALUES(?, ?, ?, ?, ?)",
+		videoID, 0, 0, 30.5, "Intro")
+	tagID := mustExec(t, db, "INSERT INTO video_tags(tag) VALUES(?)", "recipe")
+	mustExec(t, db, "INSERT INTO videos_video_tags(video_id, tag_id) VALUES(?, ?)", videoID, tagID)
+	topicID := mustExec(t, db, "INSERT INTO video_topics(name, url) VALUES(?, ?)", "Food", "https://en.wikipedia.org/wiki/Food")
+	mustExec(t, db, "INSERT INTO videos_video_topics(video_id, topic_id) VALUES(?, ?)", videoID, topicID)
+	mustExec(t, db, "INSERT INTO videos_video_categories(video_id, category_id) SELECT ?, id FROM video_categories ORDER BY id LIMIT 1", videoID)
+
+	groupID := mustExec(t, db, "INSERT INTO channel_groups(name) VALUES(?)", "favorites")
+	mustExec(t, db, "INSERT INTO channel_groups_channels(group_id, channel_id) VALUES(?, ?)", groupID, channelID)
+	mustExec(t, db, "INSERT INTO notification_rules(name, event, group_id, sink, target, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+		"uploads", "new_upload", groupID, "ntfy", "https://ntfy.sh/topic", 1700000000)
+	mustExec(t, db, "INSERT INTO notification_rules(name, event, sink, target, created_at) VALUES(?, ?, ?, ?, ?)",
+		"gone", "channel_terminated", "webhook", "http://localhost:9000/hook", 1700000000)
+
+	playlistID := mustExec(t, db, "INSERT INTO playlists(youtube_id, channel_id, title) VALUES(?, ?, ?)", "PL1", channelID, "Playlist")
+	mustExec(t, db, "INSERT INTO playlist_videos(playlist_id, position, video_youtube_id) VALUES(?, ?, ?)", playlistID, 0, "video000001")
+	mustExec(t, db, "INSERT INTO playlist_videos(playlist_id, position, video_youtube_id) VALUES(?, ?, ?)", playlistID, 1, "notimported")
+	mustExec(t, db, "INSERT INTO watch_history(video_youtube_id, title, watched_at) VALUES(?, ?, ?)", "video000001", "First video", 1700000200)
+	mustExec(t, db, "INSERT INTO search_history(query, searched_at) VALUES(?, ?)", "cooking", 1700000300)
+}
+
+// readBackup reads every stream of a backup directory.
+func readBackup(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+
+	streams := make(map[string][]byte, len(BackupStreams))
+	for _, stream := range BackupStreams {
+		data, err := os.ReadFile(filepath.Join(dir, stream+".jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		streams[stream] = data
+	}
+
+	return streams
+}
+
+// TestBackupRoundTrip restores a backup into an empty database, and checks that backing that database up again gives
+// the same backup. Restoring the backup a second time should not change anything either.
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openTestDB(t)
+	fillBackupTestDB(t, src)
+
+	tmp := t.TempDir()
+	want := filepath.Join(tmp, "want")
+	err := WriteBackup(ctx, src, want)
+	if err != nil {
+		t.Fatalf("WriteBackup: %s", err)
+	}
+
+	archive := filepath.Join(tmp, "backup.zip")
+	err = WriteBackup(ctx, src, archive)
+	if err != nil {
+		t.Fatalf("WriteBackup to zip: %s", err)
+	}
+
+	wantStreams := readBackup(t, want)
+	for stream, data := range wantStreams {
+		if len(data) == 0 {
+			t.Errorf("%s stream is empty", stream)
+		}
+	}
+
+	dest := openTestDB(t)
+	for i, backup := range []string{want, archive} {
+		err = NewBackupImporter(dest).Import(ctx, backup)
+		if err != nil {
+			t.Fatalf("Import %s: %s", backup, err)
+		}
+
+		got := filepath.Join(tmp, fmt.Sprintf("restored-%d", i))
+		err = WriteBackup(ctx, dest, got)
+		if err != nil {
+			t.Fatalf("WriteBackup of restored database: %s", err)
+		}
+
+		for stream, data := range readBackup(t, got) {
+			if !bytes.Equal(data, wantStreams[stream]) {
+				t.Errorf("restoring %s changed the %s stream:\ngot:\n%s\nwant:\n%s", filepath.Base(backup), stream, data, wantStreams[stream])
+			}
+		}
+	}
+}
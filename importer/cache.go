@@ -6,11 +6,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const CacheKeySeparator = ":"
 const defaultCacheDir = ".cache"
 
+// DefaultCacheTTL is how long cached responses are used before they are requested again. Channels can be terminated
+// and videos deleted at any time, so responses that are kept forever would hide those changes.
+const DefaultCacheTTL = 24 * time.Hour
+
 type CacheError struct{}
 
 func (e *CacheError) Error() string {
@@ -33,20 +38,21 @@ func NewCache() Cache {
 
 type fileCache struct {
 	root string
+	ttl  time.Duration
 }
 
-// NewFileCache creates a new cache that stores data as JSON files in the given directory.
+// NewFileCache creates a new cache that stores data as JSON files in the given directory. Files older than
+// DefaultCacheTTL are treated as missing, and are overwritten the next time the item is put.
 // Prefer using NewCache() instead.
 func NewFileCache(dir string) *fileCache {
-	fc := &fileCache{root: dir}
+	fc := &fileCache{root: dir, ttl: DefaultCacheTTL}
 	_ = fc.init() // FIXME how should I handle error? New*() functions don't return errors typically
 
 	return fc
 }
 
 func (fc *fileCache) Put(key string, item any) error {
-	parts := strings.Split(key, CacheKeySeparator)         // TODO maybe turn into directories?
-	fileName := strings.Join(append(parts), "-") + ".json" // FIXME might want to do some extra processing like putting stuff in directory
+	fileName := cacheKeyToFileName(key) // FIXME might want to do some extra processing like putting stuff in directory
 
 	f, err := os.Create(filepath.Join(fc.root, fileName)) // existing file will be overwritten
 	if err != nil {
@@ -72,6 +78,12 @@ func (fc *fileCache) Get(key string, item any) error {
 	if err != nil {
 		return fmt.Errorf("%s not found: %w", key, fmt.Errorf("error opening cache file: file = %s : %s\n", cacheFile, err))
 	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || fc.expired(info) {
+		return fmt.Errorf("%s not found: cache file expired: file = %s", key, cacheFile)
+	}
 
 	jd := json.NewDecoder(f)
 	err = jd.Decode(item)
@@ -83,13 +95,18 @@ func (fc *fileCache) Get(key string, item any) error {
 }
 
 // Has checks if the given key exists in the cache.
-// For FileCache, this means that the cache file exists, but does not necessarily mean you have permissions to read
-// it, or that it can be successfully deserialized.
+// For FileCache, this means that the cache file exists and has not expired, but does not necessarily mean you have
+// permissions to read it, or that it can be successfully deserialized.
 func (fc *fileCache) Has(key string) bool {
 	fileName := cacheKeyToFileName(key)
 	cacheFile := filepath.Join(fc.root, fileName)
-	_, err := os.Stat(cacheFile)
-	return err == nil
+	info, err := os.Stat(cacheFile)
+	return err == nil && !fc.expired(info)
+}
+
+// expired checks if a cache file was written more than the TTL ago.
+func (fc *fileCache) expired(info os.FileInfo) bool {
+	return fc.ttl > 0 && time.Since(info.ModTime()) > fc.ttl
 }
 
 func cacheKeyToFileName(key string) string {
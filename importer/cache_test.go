@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFileCacheExpires checks that cached responses are only used until they are DefaultCacheTTL old, and that a
+// refresh cache overwrites them without reading them.
+func TestFileCacheExpires(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewFileCache(dir)
+
+	key := cacheKey("channel", "UC0000000000000000000001")
+	err := cache.Put(key, "first")
+	if err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+
+	var item string
+	if !cache.Has(key) || cache.Get(key, &item) != nil || item != "first" {
+		t.Fatalf("new item was not cached: Has = %t, item = %q", cache.Has(key), item)
+	}
+
+	file := filepath.Join(dir, cacheKeyToFileName(key))
+	old := time.Now().Add(-DefaultCacheTTL - time.Minute)
+	err = os.Chtimes(file, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cache.Has(key) {
+		t.Error("Has returned true for an expired item")
+	}
+	if err := cache.Get(key, &item); err == nil {
+		t.Error("Get did not return an error for an expired item")
+	}
+
+	refresh := NewRefreshCache(cache)
+	if refresh.Has(key) {
+		t.Error("refresh cache returned true from Has")
+	}
+
+	err = refresh.Put(key, "second")
+	if err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+
+	if !cache.Has(key) || cache.Get(key, &item) != nil || item != "second" {
+		t.Errorf("refresh cache did not replace the expired item: Has = %t, item = %q", cache.Has(key), item)
+	}
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// VideoCategoryByTitle finds the category with the given title. yt-dlp only gives us the category title, so this is
+// how yt-dlp categories are matched to the categories YouTube uses. Categories that are not already known are added
+// without a YouTube ID.
+func VideoCategoryByTitle(ctx context.Context, db DBTX, title string) (int64, error) {
+	var id int64
+
+	// a few titles are used twice (ex. Comedy), the assignable one is what videos will actually use
+	err := db.QueryRowContext(ctx, "SELECT id FROM video_categories WHERE title = ? COLLATE NOCASE ORDER BY assignable DESC, id LIMIT 1", title).
+		Scan(&id)
+	switch {
+	case err == nil:
+		return id, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	result, err := db.ExecContext(ctx, "INSERT INTO video_categories(youtube_id, title, assignable) VALUES('', ?, false)", title)
+	if err != nil {
+		return 0, fmt.Errorf("could not add category: %s : %w", title, err)
+	}
+
+	return result.LastInsertId()
+}
+
+// VideoCategoryByYouTubeID finds the category with the given YouTube category ID. Categories that are not already
+// known are added using the given title. If the category was previously added from its title alone, its YouTube ID is
+// filled in.
+func VideoCategoryByYouTubeID(ctx context.Context, db DBTX, youtubeID string, title string) (int64, error) {
+	var id int64
+
+	err := db.QueryRowContext(ctx, "SELECT id FROM video_categories WHERE youtube_id = ?", youtubeID).
+		Scan(&id)
+	switch {
+	case err == nil:
+		return id, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	if title == "" {
+		title = youtubeID
+	}
+
+	result, err := db.ExecContext(ctx, "UPDATE video_categories SET youtube_id = ? WHERE youtube_id = '' AND title = ? COLLATE NOCASE", youtubeID, title)
+	if err != nil {
+		return 0, fmt.Errorf("could not update category: %s : %w", title, err)
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated > 0 {
+		return VideoCategoryByYouTubeID(ctx, db, youtubeID, title)
+	}
+
+	result, err = db.ExecContext(ctx, "INSERT INTO video_categories(youtube_id, title, assignable) VALUES(?, ?, false)", youtubeID, title)
+	if err != nil {
+		return 0, fmt.Errorf("could not add category: %s : %w", youtubeID, err)
+	}
+
+	return result.LastInsertId()
+}
+
+// LinkVideoCategory assigns the category to the video. The video's category_id is set to the category's YouTube ID if
+// it does not already have one.
+func LinkVideoCategory(ctx context.Context, db DBTX, videoID int64, categoryID int64) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO videos_video_categories(video_id, category_id) VALUES(?, ?)", videoID, categoryID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `UPDATE videos SET category_id = (SELECT NULLIF(youtube_id, '') FROM video_categories WHERE id = ?)
+		WHERE id = ? AND COALESCE(category_id, '') = ''`, categoryID, videoID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// saveVideoCategories makes sure the given YouTube category IDs are known, so videos can be linked to them. The
+// titles of unknown categories are looked up with the YouTube Data API.
+func (yi *YouTubeImporter) saveVideoCategories(ctx context.Context, youtubeIDs []string) error {
+	unknown := make([]string, 0)
+	for _, id := range youtubeIDs {
+		var exists bool
+		err := yi.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM video_categories WHERE youtube_id = ?)", id).
+			Scan(&exists)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			unknown = append(unknown, id)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	categories, err := yi.youtube.VideoCategories(ctx, unknown)
+	if err != nil {
+		return err
+	}
+
+	var cErrs []error
+	for _, c := range categories {
+		_, err := VideoCategoryByYouTubeID(ctx, yi.db, c.Id, c.Snippet.Title)
+		if err != nil {
+			cErrs = append(cErrs, err)
+		}
+	}
+
+	return errors.Join(cErrs...)
+}
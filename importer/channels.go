@@ -0,0 +1,696 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+	"google.golang.org/api/youtube/v3"
+)
+
+// YouTubeImporter imports channels and videos from the YouTube Data API.
+type YouTubeImporter struct {
+	db      *sql.DB
+	youtube *YouTube
+}
+
+// NewYouTubeImporter creates an importer that saves data from the YouTube Data API to the database.
+func NewYouTubeImporter(db *sql.DB, yt *YouTube) *YouTubeImporter {
+	return &YouTubeImporter{
+		db:      db,
+		youtube: yt,
+	}
+}
+
+// Resume finishes importing channels that were left over when a previous import stopped early. It reports whether
+// there was anything to resume.
+func (yi *YouTubeImporter) Resume(ctx context.Context) (bool, error) {
+	pending, err := NewQueue(yi.db, "channel").Pending(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to load import queue: %w", err)
+	}
+
+	if len(pending) == 0 {
+		return false, nil
+	}
+
+	progressFrom(ctx).Logf("Resuming previous import: %d channels remaining", len(pending))
+	return true, yi.ImportChannels(ctx, pending)
+}
+
+// ImportSubscriptions imports all the channels the authorized user is subscribed to.
+func (yi *YouTubeImporter) ImportSubscriptions(ctx context.Context) error {
+	subscriptions, err := yi.youtube.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(subscriptions) == 0 {
+		progressFrom(ctx).Logf("No subscriptions found.")
+		return nil
+	}
+
+	channelIDs := make([]string, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		channelIDs = append(channelIDs, s.Snippet.ResourceId.ChannelId)
+	}
+
+	return yi.ImportChannels(ctx, channelIDs)
+}
+
+// RefreshChannels imports every channel in the database again, so their statistics are up to date. The YouTube client
+// needs to use a refresh cache, otherwise the cached responses are used instead of requesting new ones.
+func (yi *YouTubeImporter) RefreshChannels(ctx context.Context) error {
+	channelIDs, err := getChannelYouTubeIDs(ctx, yi.db)
+	if err != nil {
+		return fmt.Errorf("unable to list channels: %w", err)
+	}
+
+	return yi.ImportChannels(ctx, channelIDs)
+}
+
+// ImportChannels imports the given channels. Channels are queued before calling the API, so if we run out of quota
+// whatever is left over can be imported by the next run. Channels can be given by handle, custom URL, or username
+// instead of by ID, see ParseChannelReference.
+func (yi *YouTubeImporter) ImportChannels(ctx context.Context, channelIDs []string) error {
+	progress := progressFrom(ctx)
+
+	channelIDs, err := yi.resolveChannelIDs(ctx, progress, channelIDs)
+	if err != nil {
+		return fmt.Errorf("unable to resolve channels: %w", err)
+	}
+
+	queue := NewQueue(yi.db, "channel")
+	err = queue.Push(ctx, channelIDs...)
+	if err != nil {
+		return fmt.Errorf("unable to queue channels: %w", err)
+	}
+
+	channels := make([]*youtube.Channel, 0, len(channelIDs))
+	requested := make([]string, 0, len(channelIDs))
+
+	var fetchErr error
+	for page := range slices.Chunk(channelIDs, MaxIDsPerRequest) {
+		response, err := yi.youtube.Channels(ctx, page)
+		if err != nil {
+			// save what we already have instead of throwing it away
+			fetchErr = err
+			progress.Logf("Stopping early: %s", err)
+			break
+		}
+
+		channels = append(channels, response...)
+		requested = append(requested, page...)
+	}
+
+	// YouTube API just does not return data for deactivated/missing channels, so anything we asked for that did not
+	// come back gets a tombstone
+	now := time.Now().Unix()
+	missing := missingChannelIDs(requested, channels)
+	if len(missing) > 0 {
+		progress.Logf("%d channels are missing:", len(missing))
+		for _, id := range missing {
+			progress.Logf("- %s", id)
+		}
+	}
+
+	disappeared, err := saveTombstones(yi.db, missing, now)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to save missing channels: %w", err))
+	}
+
+	if len(disappeared) > 0 {
+		events.Publish(ctx, events.Event{Type: events.ChannelsDisappeared, ChannelIDs: disappeared})
+	}
+
+	progress.Logf("Your Subscriptions:")
+	progress.Start("saving channels", int64(len(channels)))
+	saved := make([]int64, 0, len(channels))
+	for i, c := range channels {
+		progress.Logf("- %d, %s (Channel ID: %s)", i+1, c.Snippet.Title, c.Id)
+		if id := saveChannel(yi.db, progress, c, now); id != 0 {
+			saved = append(saved, id)
+		}
+		progress.Add(1)
+	}
+	progress.Finish()
+
+	if len(saved) > 0 {
+		events.Publish(ctx, events.Event{Type: events.ChannelsRefreshed, ChannelIDs: saved})
+	}
+
+	err = queue.Done(ctx, requested...)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to update import queue: %w", err))
+	}
+
+	quota := yi.youtube.Quota()
+	progress.Logf("Quota used today: %d units, %d remaining", quota.Used(), quota.Remaining())
+
+	if remaining := len(channelIDs) - len(requested); remaining > 0 {
+		return fmt.Errorf("%d channels were not imported: %w", remaining, fetchErr)
+	}
+
+	return nil
+}
+
+// saveChannel saves a channel along with its thumbnails, topics, and keywords, and returns its row ID. Problems are
+// reported to progress rather than returned, so one bad channel does not stop the import. The ID is 0 if the channel
+// could not be saved.
+func saveChannel(db *sql.DB, progress Progress, channel *youtube.Channel, seenAt int64) int64 {
+	// the API returned the channel, so it is no longer missing even if saving it fails
+	err := markChannelSeen(db, channel.Id, seenAt)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to mark channel as seen: %w", err))
+	}
+
+	// channels that were already imported are updated, so a refreshed import picks up any changes
+	_, err = db.Exec(`INSERT INTO channels(youtube_id, title, description, custom_url, branding_title, branding_description, subscriber_count, video_count, view_count, uploads_playlist_id, last_seen_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(youtube_id) DO UPDATE SET
+			title = excluded.title,
+			description = excluded.description,
+			custom_url = excluded.custom_url,
+			branding_title = excluded.branding_title,
+			branding_description = excluded.branding_description,
+			subscriber_count = excluded.subscriber_count,
+			video_count = excluded.video_count,
+			view_count = excluded.view_count,
+			uploads_playlist_id = excluded.uploads_playlist_id,
+			last_seen_at = excluded.last_seen_at`,
+		channel.Id,
+		channel.Snippet.Title,
+		channel.Snippet.Description,
+		channel.Snippet.CustomUrl,
+		channel.BrandingSettings.Channel.Title,
+		channel.BrandingSettings.Channel.Description,
+		channel.Statistics.SubscriberCount,
+		channel.Statistics.VideoCount,
+		channel.Statistics.ViewCount,
+		channel.ContentDetails.RelatedPlaylists.Uploads,
+		seenAt,
+	)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to save: %w", err))
+		return 0 // skip saving topic/keyword associations if we do not have a channel
+	}
+
+	// LastInsertId is not set when updating an existing channel
+	id, err := getChannelID(db, channel.Id)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to get channel row ID for subscription: %w", err))
+		return 0 // skip saving topic/keyword associations if we do not have an ID
+	}
+	channelID := int64(id)
+
+	err = saveBanner(db, channelID, channel.BrandingSettings)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to save banner: %w", err))
+	}
+
+	err = saveThumbnails(db, channelID, channel.Snippet.Thumbnails)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to save thumbnails: %w", err))
+	}
+
+	if channel.TopicDetails != nil { // work around nil pointer panic on some stuff
+		progress.Logf("...(Topics ID: %s)", channel.TopicDetails.TopicIds)
+		topicIDs, err := getTopicIDs(db, channel.TopicDetails.TopicIds)
+		if err != nil {
+			progress.Error(fmt.Errorf("unable to get topic ids: %w", err))
+		}
+
+		err = saveTopicAssociations(db, channelID, topicIDs)
+		if err != nil {
+			progress.Error(fmt.Errorf("unable to save topic ids: %w", err))
+		}
+	} else {
+		progress.Logf("...topic information not found: %#v", channel)
+	}
+
+	keywords, err := splitKeywords(channel.BrandingSettings.Channel.Keywords)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to split keywords: %w", err))
+	}
+
+	progress.Logf("...(Keywords: %#v)", keywords)
+
+	err = saveKeywords(db, keywords)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to save keywords: %w", err))
+	}
+
+	keywordIDs, err := getKeywordIDs(db, keywords)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to get keyword ids: %w", err))
+	}
+
+	err = saveKeywordAssociations(db, channelID, keywordIDs)
+	if err != nil {
+		progress.Error(fmt.Errorf("unable to save keyword ids: %w", err))
+	}
+
+	return channelID
+}
+
+func saveThumbnails(db *sql.DB, channelID int64, thumbnails *youtube.ThumbnailDetails) error {
+	var tErrs []error
+
+	if thumbnails == nil {
+		return nil
+	}
+
+	if thumbnails.Default != nil {
+		err := saveThumbnail(db, channelID, "default", thumbnails.Default)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save thumbnail: %s : %w", "default", err))
+		}
+	}
+
+	if thumbnails.High != nil {
+		err := saveThumbnail(db, channelID, "high", thumbnails.High)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save thumbnail: %s : %w", "high", err))
+		}
+	}
+
+	if thumbnails.Maxres != nil {
+		err := saveThumbnail(db, channelID, "maxres", thumbnails.Maxres)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save thumbnail: %s : %w", "maxres", err))
+		}
+	}
+
+	if thumbnails.Medium != nil {
+		err := saveThumbnail(db, channelID, "medium", thumbnails.Medium)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save thumbnail: %s : %w", "medium", err))
+		}
+	}
+
+	if thumbnails.Standard != nil {
+		err := saveThumbnail(db, channelID, "standard", thumbnails.Standard)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save thumbnail: %s : %w", "standard", err))
+		}
+	}
+
+	return errors.Join(tErrs...)
+}
+
+// saveBanner saves the channel's banner image. The banner is replaced if the channel has changed it since the last
+// import, or removed if the channel no longer has one.
+func saveBanner(db *sql.DB, channelID int64, branding *youtube.ChannelBrandingSettings) error {
+	if branding == nil || branding.Image == nil || branding.Image.BannerExternalUrl == "" {
+		_, err := db.Exec("DELETE FROM channel_banners WHERE channel_id = ?", channelID)
+		return err
+	}
+
+	_, err := db.Exec(`INSERT INTO channel_banners(channel_id, url) VALUES(?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET url = excluded.url, local_path = NULL, download_status = NULL, download_attempts = 0 WHERE url != excluded.url`,
+		channelID, branding.Image.BannerExternalUrl)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func saveThumbnail(db *sql.DB, channelID int64, size string, thumbnail *youtube.Thumbnail) error {
+	_, err := db.Exec(`INSERT INTO channel_thumbnails(channel_id, size, width, height, url) VALUES(?, ?, ?, ?, ?)
+		ON CONFLICT(channel_id, size) DO UPDATE SET width = excluded.width, height = excluded.height, url = excluded.url,
+			local_path = NULL, download_status = NULL, download_attempts = 0 WHERE url != excluded.url`,
+		channelID, size, thumbnail.Width, thumbnail.Height, thumbnail.Url)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func missingChannelIDs(requested []string, returned []*youtube.Channel) []string {
+	found := make(map[string]bool, len(returned))
+	for _, c := range returned {
+		found[c.Id] = true
+	}
+
+	missing := make([]string, 0)
+	for _, id := range requested {
+		if found[id] || slices.Contains(missing, id) {
+			continue
+		}
+
+		missing = append(missing, id)
+	}
+
+	return missing
+}
+
+// saveTombstones records channels that were requested, but not returned by the YouTube API. The first time a channel
+// goes missing is kept, so re-running the import only updates when it was last checked. The row IDs of imported
+// channels that just went missing are returned.
+func saveTombstones(db *sql.DB, youtubeIDs []string, checkedAt int64) ([]int64, error) {
+	var tErrs []error
+	var disappeared []int64
+
+	for _, id := range youtubeIDs {
+		var channelID sql.NullInt64
+		var firstMissingAt int64
+		err := db.QueryRow(`INSERT INTO channel_tombstones(youtube_id, channel_id, first_missing_at, last_checked_at, last_seen_at)
+			VALUES(?, (SELECT id FROM channels WHERE youtube_id = ?), ?, ?, (SELECT last_seen_at FROM channels WHERE youtube_id = ?))
+			ON CONFLICT(youtube_id) DO UPDATE SET last_checked_at = excluded.last_checked_at
+			RETURNING channel_id, first_missing_at`,
+			id, id, checkedAt, checkedAt, id).Scan(&channelID, &firstMissingAt)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save tombstone: %s : %w", id, err))
+			continue
+		}
+
+		if channelID.Valid && firstMissingAt == checkedAt {
+			disappeared = append(disappeared, channelID.Int64)
+		}
+	}
+
+	return disappeared, errors.Join(tErrs...)
+}
+
+// markChannelSeen records that the YouTube API returned the channel. Channels that come back after going missing
+// no longer need a tombstone. It is called before the channel is saved, so channels that are new to the database get
+// last_seen_at when they are inserted.
+func markChannelSeen(db *sql.DB, youtubeID string, seenAt int64) error {
+	_, err := db.Exec("UPDATE channels SET last_seen_at = ? WHERE youtube_id = ?", seenAt, youtubeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM channel_tombstones WHERE youtube_id = ?", youtubeID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getChannelID(db *sql.DB, youtubeID string) (int, error) {
+	var id int
+
+	err := db.QueryRow("SELECT id FROM channels WHERE youtube_id = ?", youtubeID).
+		Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return id, err
+	case err != nil:
+		return id, err
+	default:
+		return id, nil
+	}
+}
+
+func saveKeywordAssociations(db *sql.DB, channelID int64, keywordIDs []int) error {
+	// FIXME since keyword should be unique we could probably do this with an insert with select/join to automatically
+	//  fetch the ids
+	if len(keywordIDs) == 0 {
+		return nil
+	}
+
+	for _, keywordID := range keywordIDs {
+		result, err := db.Exec("INSERT INTO channels_keywords(channel_id, keyword_id) VALUES(?, ?)", channelID, keywordID)
+		if err != nil {
+			return err
+		}
+
+		_, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func saveKeywords(db *sql.DB, keywords []string) error {
+	// TODO probably want to remove '#' from hashtag keywords at some point
+	// TODO seems like some people also use multiple hashtags as a single keyword. may want to additionally split that
+	if len(keywords) == 0 {
+		return nil
+	}
+
+	for _, k := range keywords {
+		result, err := db.Exec("INSERT INTO keywords(keyword) VALUES(?)", k)
+		if err != nil {
+			return err
+		}
+
+		_, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getKeywordIDs(db *sql.DB, keywords []string) ([]int, error) {
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+
+	// handle IN clause placeholders
+	keywordPlaceholders := strings.Repeat("?,", len(keywords))
+	keywordPlaceholders = keywordPlaceholders[:len(keywordPlaceholders)-1] // strip off the trailing ,
+	args := make([]interface{}, 0, len(keywords))
+	for _, id := range keywords {
+		args = append(args, id)
+	}
+
+	queryTopicIDs := fmt.Sprintf("SELECT id FROM keywords WHERE keyword in (%s)", keywordPlaceholders)
+	rows, err := db.Query(queryTopicIDs, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0, len(keywords))
+	for rows.Next() {
+		var keywordID int
+		if err := rows.Scan(&keywordID); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, keywordID)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// splitKeywords splits the list of keywords provided by the YouTube data API.
+// The keywords are separated by a space, but if a keyword should contain
+// multiple words then those words will be quoted. This format allows us to
+// treat the keyword list as a space-separated CSV record.
+func splitKeywords(s string) ([]string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	keywords := make([]string, 0)
+
+	buf := strings.NewReader(s)
+
+	splitter := csv.NewReader(buf)
+	splitter.Comma = ' '
+
+	records, err := splitter.ReadAll()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	for _, r := range records {
+		// there should only be one record, but we'll assume that's not the case
+		for _, k := range r {
+			keywords = append(keywords, strings.ToLower(k))
+		}
+	}
+
+	return keywords, nil
+}
+
+func saveTopicAssociations(db *sql.DB, channelID int64, topicIDs []int) error {
+	if len(topicIDs) == 0 {
+		return nil
+	}
+
+	for _, topicID := range topicIDs {
+		result, err := db.Exec("INSERT INTO channels_topics(channel_id, topic_id) VALUES(?, ?)", channelID, topicID)
+		if err != nil {
+			return err
+		}
+
+		_, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getTopicIDs(db *sql.DB, topicIDs []string) ([]int, error) {
+	if len(topicIDs) == 0 {
+		return nil, nil
+	}
+
+	// handle IN clause placeholders
+	topicPlaceholders := strings.Repeat("?,", len(topicIDs))
+	topicPlaceholders = topicPlaceholders[:len(topicPlaceholders)-1] // strip off the trailing ,
+	args := make([]interface{}, 0, len(topicIDs))
+	for _, id := range topicIDs {
+		args = append(args, id)
+	}
+
+	queryTopicIDs := fmt.Sprintf("SELECT id FROM topics WHERE topic_id in (%s)", topicPlaceholders)
+	rows, err := db.Query(queryTopicIDs, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0, len(topicIDs))
+	for rows.Next() {
+		var topicID int
+		if err := rows.Scan(&topicID); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, topicID)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// resolveChannelIDs turns handles, custom URLs, and usernames into channel IDs. Anything else is assumed to already be
+// a channel ID. Channels that could not be found are listed and skipped.
+func (yi *YouTubeImporter) resolveChannelIDs(ctx context.Context, progress Progress, refs []string) ([]string, error) {
+	ids := make([]string, 0, len(refs))
+	var unresolved []string
+
+	for _, r := range refs {
+		ref, ok := ParseChannelReference(r)
+		switch {
+		case !ok:
+			ids = append(ids, r)
+			continue
+		case ref.Kind == ChannelRefID:
+			ids = append(ids, ref.Value)
+			continue
+		}
+
+		id, err := yi.resolveChannel(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve channel: %s : %w", r, err)
+		}
+
+		if id == "" {
+			unresolved = append(unresolved, r)
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	if len(unresolved) > 0 {
+		progress.Logf("%d channels could not be found:", len(unresolved))
+		for _, r := range unresolved {
+			progress.Logf("- %s", r)
+		}
+	}
+
+	return uniqueIDs(ids), nil
+}
+
+// resolveChannel finds the ID of a channel given by handle, custom URL, or username. Channels that have already been
+// imported are found by their custom URL without using the API. An empty ID is returned if there is no such channel.
+func (yi *YouTubeImporter) resolveChannel(ctx context.Context, ref ChannelReference) (string, error) {
+	if ref.Kind != ChannelRefUsername {
+		var id string
+		err := yi.db.QueryRowContext(ctx, "SELECT youtube_id FROM channels WHERE custom_url COLLATE NOCASE IN (?, ?)", "@"+ref.Value, ref.Value).
+			Scan(&id)
+		switch {
+		case err == nil:
+			return id, nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return "", err
+		}
+	}
+
+	switch ref.Kind {
+	case ChannelRefHandle:
+		return yi.youtube.ChannelIDForHandle(ctx, ref.Value)
+	case ChannelRefCustom:
+		// custom URLs were turned into handles, and most channels kept the same name
+		id, err := yi.youtube.ChannelIDForHandle(ctx, ref.Value)
+		if err != nil || id != "" {
+			return id, err
+		}
+
+		return yi.youtube.ChannelIDForUsername(ctx, ref.Value)
+	default:
+		return yi.youtube.ChannelIDForUsername(ctx, ref.Value)
+	}
+}
+
+// ReadChannelsCSV reads channel IDs from a CSV file, such as the subscriptions.csv file from Google Takeout. The file
+// needs a header row and the channel in the first column. The channel can be an ID, an @handle, or a channel URL.
+func ReadChannelsCSV(file string) ([]string, error) {
+	if len(file) == 0 {
+		// no file given
+		return nil, errors.New("no input file given")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	splitter := csv.NewReader(f)
+
+	records, err := splitter.ReadAll()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	channels := make([]string, 0, len(records))
+
+	for _, r := range records[1:] {
+		channels = append(channels, strings.TrimSpace(r[0]))
+	}
+
+	return channels, nil
+}
+
+const DATABASE_FILE = "youtube.sqlite"
+const SECRET_FILE = "client_secret.json"
@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// TestResolveChannelIDs checks that handles, custom URLs, and usernames are looked up with the API, and that channels
+// already in the database are found by their custom URL without using any quota.
+func TestResolveChannelIDs(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title, custom_url) VALUES(?, ?, ?)", "UC0000000000000000000001", "Imported", "@imported")
+
+	handles := map[string]string{"handle": "UC0000000000000000000002"}
+	usernames := map[string]string{"oldname": "UC0000000000000000000003"}
+
+	var requests []string
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		requests = append(requests, query.Encode())
+
+		id := handles[strings.ToLower(query.Get("forHandle"))]
+		if username := query.Get("forUsername"); username != "" {
+			id = usernames[strings.ToLower(username)]
+		}
+
+		response := youtube.ChannelListResponse{}
+		if id != "" {
+			response.Items = append(response.Items, &youtube.Channel{Id: id})
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	})
+
+	yi := NewYouTubeImporter(db, newTestYouTube(t, api, newTestQuota(t, db, 100)))
+	progress := &testProgress{}
+	ids, err := yi.resolveChannelIDs(ctx, progress, []string{
+		"UC0000000000000000000004",
+		"https://www.youtube.com/@Imported",
+		"@handle",
+		"https://www.youtube.com/c/OldName",
+		"https://www.youtube.com/user/oldname",
+		"@nobody",
+	})
+	if err != nil {
+		t.Fatalf("resolveChannelIDs: %s", err)
+	}
+
+	want := []string{"UC0000000000000000000004", "UC0000000000000000000001", "UC0000000000000000000002", "UC0000000000000000000003"}
+	if !slices.Equal(ids, want) {
+		t.Errorf("resolveChannelIDs = %v, want %v", ids, want)
+	}
+
+	// @handle, /c/OldName as a handle then a username, /user/oldname, and @nobody
+	if len(requests) != 5 {
+		t.Errorf("made %d requests, want 5: %v", len(requests), requests)
+	}
+
+	if !slices.Contains(progress.lines, "- @nobody") {
+		t.Errorf("unresolved channel was not reported: %v", progress.lines)
+	}
+}
@@ -0,0 +1,13 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so the same queries can be run inside or outside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
@@ -0,0 +1,185 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// DownloadArchiveImporter imports the download archive files yt-dlp writes with --download-archive. Download archives
+// only list the IDs of the videos that were downloaded, so the rest of the video information has to come from the
+// YouTube Data API.
+type DownloadArchiveImporter struct {
+	db      *sql.DB
+	cache   Cache
+	youtube *YouTube
+}
+
+// NewDownloadArchiveImporter creates a download archive importer. If yt is nil, videos are only looked up in the cache
+// of earlier API responses.
+func NewDownloadArchiveImporter(db *sql.DB, cache Cache, yt *YouTube) *DownloadArchiveImporter {
+	return &DownloadArchiveImporter{
+		db:      db,
+		cache:   cache,
+		youtube: yt,
+	}
+}
+
+// Import marks every YouTube video in the download archive as archived. Videos that are not in the database yet are
+// added when their channel is known. The IDs of videos that could not be attributed to a channel are printed at the
+// end.
+func (di *DownloadArchiveImporter) Import(ctx context.Context, file string) error {
+	ids, skipped, err := ReadDownloadArchive(file)
+	if err != nil {
+		return fmt.Errorf("can not read download archive: file = \"%s\": %w", file, err)
+	}
+	fmt.Printf("%d YouTube videos in download archive, skipped %d from other sites\n", len(ids), skipped)
+
+	unknown, err := di.markArchived(ctx, ids)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d videos were already in the database\n", len(ids)-len(unknown))
+
+	videos, err := di.lookup(ctx, unknown)
+	if err != nil {
+		// save what we found before the error
+		fmt.Printf("...unable to look up videos: %s\n", err)
+	}
+
+	added := make(map[string]bool)
+	var vErrs []error
+	for _, v := range videos {
+		if v.Snippet == nil {
+			continue
+		}
+
+		var channelID int64
+		err := di.db.QueryRowContext(ctx, "SELECT id FROM channels WHERE youtube_id = ?", v.Snippet.ChannelId).
+			Scan(&channelID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			vErrs = append(vErrs, fmt.Errorf("%s: missing channel: channel ID = \"%s\": %w", v.Id, v.Snippet.ChannelId, err))
+			continue
+		}
+
+		err = saveAPIVideo(ctx, di.db, channelID, v)
+		if err != nil {
+			vErrs = append(vErrs, fmt.Errorf("%s: failed to save video: %w", v.Id, err))
+			continue
+		}
+
+		_, err = di.db.ExecContext(ctx, "UPDATE videos SET is_archived = TRUE WHERE youtube_id = ?", v.Id)
+		if err != nil {
+			vErrs = append(vErrs, fmt.Errorf("%s: unable to mark video as archived: %w", v.Id, err))
+			continue
+		}
+
+		added[v.Id] = true
+	}
+	fmt.Printf("%d videos were added\n", len(added))
+
+	unknown = slices.DeleteFunc(unknown, func(id string) bool { return added[id] })
+	if len(unknown) > 0 {
+		fmt.Printf("%d videos could not be attributed to a channel:\n", len(unknown))
+		for _, id := range unknown {
+			fmt.Printf("- %s\n", id)
+		}
+	}
+
+	return errors.Join(vErrs...)
+}
+
+// markArchived marks the videos that are already in the database as archived and returns the IDs of the videos that
+// are not.
+func (di *DownloadArchiveImporter) markArchived(ctx context.Context, ids []string) ([]string, error) {
+	tx, err := di.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE videos SET is_archived = TRUE WHERE youtube_id = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	unknown := make([]string, 0)
+	for _, id := range ids {
+		result, err := stmt.ExecContext(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: unable to mark video as archived: %w", id, err)
+		}
+
+		if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+			unknown = append(unknown, id)
+		}
+	}
+
+	return unknown, tx.Commit()
+}
+
+// lookup gets the videos from the cache, or from the YouTube Data API if it is available. Videos that no longer exist
+// are not returned.
+func (di *DownloadArchiveImporter) lookup(ctx context.Context, ids []string) ([]*youtube.Video, error) {
+	if di.youtube == nil {
+		videos, _ := cachedItems[youtube.Video](di.cache, "video", ids)
+		return videos, nil
+	}
+
+	videos := make([]*youtube.Video, 0, len(ids))
+	for page := range slices.Chunk(ids, MaxIDsPerRequest) {
+		found, err := di.youtube.Videos(ctx, page)
+		videos = append(videos, found...)
+		if err != nil {
+			return videos, err
+		}
+	}
+
+	return videos, nil
+}
+
+// ReadDownloadArchive reads the YouTube video IDs from a yt-dlp download archive. Each line is the extractor name
+// followed by the video ID. The number of lines for other extractors is also returned.
+func ReadDownloadArchive(file string) ([]string, int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var ids []string
+	seen := make(map[string]bool)
+	skipped := 0
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		extractor, id, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+
+		if !strings.EqualFold(extractor, "youtube") {
+			skipped++
+			continue
+		}
+
+		id = strings.TrimSpace(id)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, skipped, scanner.Err()
+}
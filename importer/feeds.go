@@ -0,0 +1,353 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+)
+
+// DefaultFeedURL is the base URL of the YouTube channel RSS feeds. The channel is given with the channel_id parameter.
+const DefaultFeedURL = "https://www.youtube.com/feeds/videos.xml"
+
+// feedWorkers is the number of feeds downloaded at the same time.
+const feedWorkers = 4
+
+// atomFeed is a channel's RSS feed, which is actually an Atom feed. It only has the 15 most recent uploads.
+type atomFeed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	VideoID   string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+	ChannelID string `xml:"http://www.youtube.com/xml/schemas/2015 channelId"`
+	Title     string `xml:"title"`
+	Published string `xml:"published"`
+	Link      struct {
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Group struct {
+		Description string `xml:"description"`
+		Thumbnail   struct {
+			URL    string `xml:"url,attr"`
+			Width  int64  `xml:"width,attr"`
+			Height int64  `xml:"height,attr"`
+		} `xml:"thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// channelFeed is a channel whose feed is going to be checked, along with the headers from the last check.
+type channelFeed struct {
+	channelID    int64
+	youtubeID    string
+	etag         string
+	lastModified string
+	lastStatus   int  // 0 if the feed has not been checked before
+	seen         bool // the feed has been downloaded before
+
+	// set once the feed has been checked
+	status  int
+	entries []atomEntry
+	err     error
+}
+
+// FeedPoller finds new uploads from the channel RSS feeds. Feeds do not use any YouTube Data API quota, so they can be
+// checked often. Only the title, publish time, description, and thumbnail are known until the video is imported with
+// the API or yt-dlp.
+type FeedPoller struct {
+	db      *sql.DB
+	baseURL string
+	client  *http.Client
+}
+
+// NewFeedPoller creates a feed poller. The feeds are requested from baseURL, which defaults to DefaultFeedURL.
+func NewFeedPoller(db *sql.DB, baseURL string) *FeedPoller {
+	if baseURL == "" {
+		baseURL = DefaultFeedURL
+	}
+
+	return &FeedPoller{
+		db:      db,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Poll checks the feeds of the given channels for new videos. If no channels are given, every channel in the database
+// is checked. Feeds are requested with the ETag and Last-Modified headers from the last check, so feeds that have not
+// changed are not downloaded again.
+func (fp *FeedPoller) Poll(ctx context.Context, youtubeIDs []string) error {
+	feeds, err := fp.channelFeeds(ctx, youtubeIDs)
+	if err != nil {
+		return fmt.Errorf("unable to list channels: %w", err)
+	}
+
+	pending := make(chan *channelFeed)
+	checked := make(chan *channelFeed)
+
+	go func() {
+		defer close(pending)
+		for _, f := range feeds {
+			select {
+			case pending <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range feedWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range pending {
+				fp.fetch(ctx, f)
+				checked <- f
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(checked)
+	}()
+
+	progress := progressFrom(ctx)
+	progress.Start("checking feeds", int64(len(feeds)))
+
+	var notModified, newVideos int
+	var fErrs []error
+	for f := range checked {
+		progress.Add(1)
+
+		if f.err != nil {
+			fErrs = append(fErrs, fmt.Errorf("unable to check feed: %s: %w", f.youtubeID, f.err))
+			continue
+		}
+
+		if f.status == http.StatusNotModified {
+			notModified++
+		}
+
+		videoIDs, err := fp.save(ctx, f)
+		if err != nil {
+			fErrs = append(fErrs, fmt.Errorf("unable to check feed: %s: %w", f.youtubeID, err))
+		}
+		newVideos += len(videoIDs)
+
+		// the first download adds the channel's recent uploads, which are not new, so they are not reported
+		if len(videoIDs) > 0 && f.seen {
+			events.Publish(ctx, events.Event{Type: events.VideosDiscovered, ChannelIDs: []int64{f.channelID}, VideoIDs: videoIDs})
+		}
+
+		// only channels that used to have a feed, so the first check does not report every channel that is already gone
+		if f.status == http.StatusNotFound && f.seen && f.lastStatus != http.StatusNotFound {
+			events.Publish(ctx, events.Event{Type: events.ChannelsDisappeared, ChannelIDs: []int64{f.channelID}})
+		}
+	}
+	progress.Finish()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	progress.Logf("checked %d feeds, %d not modified, found %d new videos, %d failed", len(feeds), notModified, newVideos, len(fErrs))
+
+	return errors.Join(fErrs...)
+}
+
+// channelFeeds lists the channels to check along with the headers saved from the last check.
+func (fp *FeedPoller) channelFeeds(ctx context.Context, youtubeIDs []string) ([]*channelFeed, error) {
+	rows, err := fp.db.QueryContext(ctx, `SELECT channels.id, channels.youtube_id, COALESCE(channel_feeds.etag, ''), COALESCE(channel_feeds.last_modified, ''), COALESCE(channel_feeds.status, 0), channel_feeds.downloaded_at IS NOT NULL
+		FROM channels
+		LEFT JOIN channel_feeds ON channel_feeds.channel_id = channels.id
+		ORDER BY channels.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(youtubeIDs))
+	for _, id := range youtubeIDs {
+		wanted[id] = true
+	}
+
+	var feeds []*channelFeed
+	for rows.Next() {
+		f := &channelFeed{}
+		if err := rows.Scan(&f.channelID, &f.youtubeID, &f.etag, &f.lastModified, &f.lastStatus, &f.seen); err != nil {
+			return nil, err
+		}
+
+		if len(wanted) == 0 || wanted[f.youtubeID] {
+			feeds = append(feeds, f)
+		}
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
+
+// fetch downloads and parses the channel's feed. A 304 Not Modified response leaves the entries empty.
+func (fp *FeedPoller) fetch(ctx context.Context, f *channelFeed) {
+	u, err := url.Parse(fp.baseURL)
+	if err != nil {
+		f.err = fmt.Errorf("invalid feed URL: %w", err)
+		return
+	}
+
+	query := u.Query()
+	query.Set("channel_id", f.youtubeID)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		f.err = err
+		return
+	}
+
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	if f.lastModified != "" {
+		req.Header.Set("If-Modified-Since", f.lastModified)
+	}
+
+	resp, err := fp.client.Do(req)
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer resp.Body.Close()
+
+	f.status = resp.StatusCode
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return
+	case http.StatusNotFound:
+		// terminated channels stop having a feed, saving the status keeps a record of when it went away
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return
+	default:
+		f.err = fmt.Errorf("unexpected status: %s", resp.Status)
+		return
+	}
+
+	var feed atomFeed
+	err = xml.NewDecoder(resp.Body).Decode(&feed)
+	if err != nil {
+		f.err = fmt.Errorf("could not parse feed: %w", err)
+		return
+	}
+
+	f.entries = feed.Entries
+	f.etag = resp.Header.Get("ETag")
+	f.lastModified = resp.Header.Get("Last-Modified")
+}
+
+// save adds the videos that are not in the database yet and records the check. The row IDs of the new videos are
+// returned.
+func (fp *FeedPoller) save(ctx context.Context, f *channelFeed) ([]int64, error) {
+	tx, err := fp.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var videoIDs []int64
+	var eErrs []error
+	for _, e := range f.entries {
+		videoID, err := saveFeedEntry(ctx, tx, f.channelID, e)
+		if err != nil {
+			eErrs = append(eErrs, fmt.Errorf("could not save video: %s : %w", e.VideoID, err))
+		}
+
+		if videoID != 0 {
+			videoIDs = append(videoIDs, videoID)
+		}
+	}
+
+	now := time.Now().Unix()
+	var downloadedAt *int64
+	if f.status == http.StatusOK || f.status == http.StatusNotModified {
+		downloadedAt = &now
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO channel_feeds(channel_id, etag, last_modified, status, checked_at, downloaded_at) VALUES(?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET
+			etag = excluded.etag,
+			last_modified = excluded.last_modified,
+			status = excluded.status,
+			checked_at = excluded.checked_at,
+			downloaded_at = COALESCE(excluded.downloaded_at, downloaded_at)`,
+		f.channelID, f.etag, f.lastModified, f.status, now, downloadedAt)
+	if err != nil {
+		return nil, errors.Join(append(eErrs, fmt.Errorf("could not save feed status: %w", err))...)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return videoIDs, errors.Join(eErrs...)
+}
+
+// saveFeedEntry adds the video from a feed entry and returns its row ID. Videos that are already in the database are
+// left alone, since the feed has less information than the API or yt-dlp, and 0 is returned for them.
+func saveFeedEntry(ctx context.Context, db DBTX, channelID int64, e atomEntry) (int64, error) {
+	if e.VideoID == "" {
+		return 0, errors.New("entry has no video ID")
+	}
+
+	var publishedAt *int64
+	if t, err := time.Parse(time.RFC3339, e.Published); err == nil {
+		ts := t.Unix()
+		publishedAt = &ts
+	}
+
+	result, err := db.ExecContext(ctx, "INSERT INTO videos(youtube_id, channel_id, title, description, published_at, webpage_url) VALUES(?, ?, ?, ?, ?, ?)",
+		e.VideoID, channelID, e.Title, e.Group.Description, publishedAt, e.Link.Href)
+	if err != nil {
+		return 0, err
+	}
+
+	// ignored by the unique constraint if the video is already known
+	inserted, err := result.RowsAffected()
+	if err != nil || inserted == 0 {
+		return 0, err
+	}
+
+	videoID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if e.Group.Thumbnail.URL != "" {
+		// feeds have the 480x360 thumbnail, which the API calls high
+		_, err = db.ExecContext(ctx, "INSERT INTO video_thumbnails(video_id, size, width, height, url) VALUES(?, ?, ?, ?, ?)",
+			videoID, "high", e.Group.Thumbnail.Width, e.Group.Thumbnail.Height, e.Group.Thumbnail.URL)
+		if err != nil {
+			return videoID, fmt.Errorf("could not save thumbnail: %w", err)
+		}
+	}
+
+	return videoID, nil
+}
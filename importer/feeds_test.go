@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+)
+
+// testFeed is a channel RSS feed with a single upload.
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns:media="http://search.yahoo.com/mrss/" xmlns="http://www.w3.org/2005/Atom">
+ <entry>
+  <yt:videoId>feedvideo01</yt:videoId>
+  <yt:channelId>UC0000000000000000000001</yt:channelId>
+  <title>Feed video</title>
+  <link rel="alternate" href="https://www.youtube.com/watch?v=feedvideo01"/>
+  <published>2024-01-02T03:04:05+00:00</published>
+  <media:group>
+   <media:thumbnail url="https://i.ytimg.com/vi/feedvideo01/hqdefault.jpg" width="480" height="360"/>
+   <media:description>From the feed</media:description>
+  </media:group>
+ </entry>
+</feed>`
+
+// TestPollSavesFeedVideos checks that the videos in a feed are saved, and that feeds that could not be checked are
+// returned as an error without stopping the other feeds.
+func TestPollSavesFeedVideos(t *testing.T) {
+	ctx := WithProgress(context.Background(), &testProgress{})
+	db := openTestDB(t)
+
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000002", "Broken")
+
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("channel_id") != "UC0000000000000000000001" {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml")
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	defer feeds.Close()
+
+	err := NewFeedPoller(db, feeds.URL).Poll(ctx, nil)
+	if err == nil {
+		t.Error("Poll did not return an error for the broken feed")
+	}
+
+	var youtubeID, title, description string
+	var publishedAt int64
+	err = db.QueryRow("SELECT youtube_id, title, description, published_at FROM videos").Scan(&youtubeID, &title, &description, &publishedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if youtubeID != "feedvideo01" || title != "Feed video" || description != "From the feed" || publishedAt != 1704164645 {
+		t.Errorf("saved %s, %q, %q, %d", youtubeID, title, description, publishedAt)
+	}
+
+	var thumbnails int
+	err = db.QueryRow("SELECT COUNT(*) FROM video_thumbnails WHERE size = 'high' AND width = 480").Scan(&thumbnails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbnails != 1 {
+		t.Errorf("%d thumbnails were saved, want 1", thumbnails)
+	}
+}
+
+// TestPollConditionalRequest checks that the second check of a feed sends the ETag and Last-Modified headers from the
+// first one, and that a 304 Not Modified response is recorded without adding anything.
+func TestPollConditionalRequest(t *testing.T) {
+	ctx := WithProgress(context.Background(), &testProgress{})
+	db := openTestDB(t)
+
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+
+	const etag = `"feed-v1"`
+	const lastModified = "Tue, 02 Jan 2024 03:04:05 GMT"
+
+	var mu sync.Mutex
+	var headers []http.Header
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	defer feeds.Close()
+
+	poller := NewFeedPoller(db, feeds.URL)
+	for range 2 {
+		err := poller.Poll(ctx, nil)
+		if err != nil {
+			t.Fatalf("Poll: %s", err)
+		}
+	}
+
+	if len(headers) != 2 {
+		t.Fatalf("feed was requested %d times, want 2", len(headers))
+	}
+	if headers[0].Get("If-None-Match") != "" || headers[0].Get("If-Modified-Since") != "" {
+		t.Errorf("first check sent conditional headers: %v", headers[0])
+	}
+	if headers[1].Get("If-None-Match") != etag || headers[1].Get("If-Modified-Since") != lastModified {
+		t.Errorf("second check sent If-None-Match = %q, If-Modified-Since = %q", headers[1].Get("If-None-Match"), headers[1].Get("If-Modified-Since"))
+	}
+
+	var videos, status int
+	var savedETag, savedLastModified string
+	err := db.QueryRow("SELECT (SELECT COUNT(*) FROM videos), status, etag, last_modified FROM channel_feeds").
+		Scan(&videos, &status, &savedETag, &savedLastModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if videos != 1 || status != http.StatusNotModified || savedETag != etag || savedLastModified != lastModified {
+		t.Errorf("after a 304: %d videos, status %d, ETag %q, Last-Modified %q", videos, status, savedETag, savedLastModified)
+	}
+}
+
+// TestPollReportsOnlyNewUploads checks that the uploads already in a feed are not reported as new the first time it is
+// downloaded, even when earlier checks got a 404, and that uploads added after that are.
+func TestPollReportsOnlyNewUploads(t *testing.T) {
+	bus := events.NewBus()
+	ctx := events.NewContext(WithProgress(context.Background(), &testProgress{}), bus)
+	db := openTestDB(t)
+
+	mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+
+	responses := []string{"", testFeed, strings.Replace(testFeed, "<entry>", testFeedEntry+"\n <entry>", 1)}
+	var check int
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed := responses[check]
+		check++
+
+		if feed == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer feeds.Close()
+
+	received, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	poller := NewFeedPoller(db, feeds.URL)
+	for range responses {
+		err := poller.Poll(ctx, nil)
+		if err != nil {
+			t.Fatalf("Poll: %s", err)
+		}
+	}
+	unsubscribe()
+
+	var discovered []events.Event
+	for e := range received {
+		discovered = append(discovered, e)
+	}
+
+	if len(discovered) != 1 || discovered[0].Type != events.VideosDiscovered || len(discovered[0].VideoIDs) != 1 {
+		t.Fatalf("published %+v, want one videos_discovered event with one video", discovered)
+	}
+
+	var youtubeID string
+	err := db.QueryRow("SELECT youtube_id FROM videos WHERE id = ?", discovered[0].VideoIDs[0]).Scan(&youtubeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if youtubeID != "feedvideo02" {
+		t.Errorf("reported %s as new, want feedvideo02", youtubeID)
+	}
+}
+
+// testFeedEntry is an upload added to testFeed after it was first downloaded.
+const testFeedEntry = `<entry>
+  <yt:videoId>feedvideo02</yt:videoId>
+  <yt:channelId>UC0000000000000000000001</yt:channelId>
+  <title>Newer feed video</title>
+  <link rel="alternate" href="https://www.youtube.com/watch?v=feedvideo02"/>
+  <published>2024-01-03T03:04:05+00:00</published>
+ </entry>`
@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StdinInput is the input name used to read JSON from stdin instead of a file. Stdin can have any number of JSON
+// objects, such as the output of `yt-dlp --dump-json`.
+const StdinInput = "-"
+
+// ytdlpSource is a JSON file to import. Files on disk are read by the workers, but archive entries and stdin have to be
+// read in order, so their data is read up front.
+type ytdlpSource struct {
+	path string
+	data []byte
+	err  error
+}
+
+// ytdlpInputs are the inputs to import, sorted by how they need to be read.
+type ytdlpInputs struct {
+	files    []string
+	archives []string
+	stdin    bool
+}
+
+// findYTDLPInputs sorts the inputs by type and recursively finds all the JSON files in directories.
+func findYTDLPInputs(inputs []string) (*ytdlpInputs, error) {
+	in := &ytdlpInputs{}
+
+	for _, input := range inputs {
+		if input == StdinInput {
+			in.stdin = true
+			continue
+		}
+
+		// files are recorded by absolute path, so it does not matter where the import is run from
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case info.IsDir():
+			files, err := findJSONFiles(abs)
+			if err != nil {
+				return nil, err
+			}
+			in.files = append(in.files, files...)
+		case isArchive(abs):
+			in.archives = append(in.archives, abs)
+		default:
+			in.files = append(in.files, abs)
+		}
+	}
+
+	return in, nil
+}
+
+// count is the number of files that will be imported, or -1 if that can not be known without reading every input.
+func (in *ytdlpInputs) count() int64 {
+	if in.stdin {
+		return -1
+	}
+
+	count := int64(len(in.files))
+	for _, archive := range in.archives {
+		if !strings.HasSuffix(strings.ToLower(archive), ".zip") {
+			// tar files do not have an index
+			return -1
+		}
+
+		r, err := zip.OpenReader(archive)
+		if err != nil {
+			// the error is reported when the archive is read
+			continue
+		}
+
+		for _, f := range r.File {
+			if isJSONFile(f.Name) {
+				count++
+			}
+		}
+		_ = r.Close()
+	}
+
+	return count
+}
+
+// read sends every input to sources. Inputs that can not be read are sent with an error.
+func (in *ytdlpInputs) read(ctx context.Context, sources chan<- ytdlpSource) {
+	send := func(src ytdlpSource) bool {
+		select {
+		case sources <- src:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, f := range in.files {
+		if !send(ytdlpSource{path: f}) {
+			return
+		}
+	}
+
+	for _, archive := range in.archives {
+		var err error
+		if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+			err = readZip(archive, send)
+		} else {
+			err = readTar(archive, send)
+		}
+
+		if err != nil && !send(ytdlpSource{path: archive, err: err}) {
+			return
+		}
+	}
+
+	if in.stdin {
+		readJSONStream(os.Stdin, send)
+	}
+}
+
+// readZip sends every JSON file in a zip archive. Entries are recorded as if the archive was a directory.
+func readZip(archive string, send func(ytdlpSource) bool) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("could not open archive: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !isJSONFile(f.Name) {
+			continue
+		}
+
+		src := ytdlpSource{path: archive + "/" + f.Name}
+
+		rc, err := f.Open()
+		if err == nil {
+			src.data, err = io.ReadAll(rc)
+			_ = rc.Close()
+		}
+		src.err = err
+
+		if !send(src) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// readTar sends every JSON file in a tar archive, which can be gzipped. Entries are recorded as if the archive was a
+// directory.
+func readTar(archive string, send func(ytdlpSource) bool) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("could not open archive: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	lower := strings.ToLower(archive)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("could not open archive: %w", err)
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg || !isJSONFile(header.Name) {
+			continue
+		}
+
+		src := ytdlpSource{path: archive + "/" + header.Name}
+		src.data, src.err = io.ReadAll(tr)
+
+		if !send(src) {
+			return nil
+		}
+	}
+}
+
+// readJSONStream sends every JSON object in r.
+func readJSONStream(r io.Reader, send func(ytdlpSource) bool) {
+	dec := json.NewDecoder(r)
+	for {
+		var data json.RawMessage
+		err := dec.Decode(&data)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+
+		src := ytdlpSource{path: StdinInput, data: data, err: err}
+		if !send(src) || err != nil {
+			// the rest of the stream can not be decoded after an error
+			return
+		}
+	}
+}
+
+// findJSONFiles recursively finds all the JSON files in dir.
+func findJSONFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !isJSONFile(d.Name()) {
+			return nil
+		}
+
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan directory: dir = \"%s\": %w", dir, err)
+	}
+
+	return files, nil
+}
+
+func isJSONFile(name string) bool {
+	return filepath.Ext(name) == ".json"
+}
+
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
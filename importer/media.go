@@ -0,0 +1,367 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// media file statuses set by Verify
+const (
+	MediaOK        = "ok"
+	MediaMissing   = "missing"
+	MediaTruncated = "truncated"
+	MediaModified  = "modified"
+)
+
+// mediaExtensions are the file extensions yt-dlp uses for downloaded videos and audio.
+var mediaExtensions = []string{
+	".mp4", ".mkv", ".webm", ".mov", ".flv", ".avi", ".3gp",
+	".m4a", ".mp3", ".opus", ".ogg", ".aac", ".flac", ".wav",
+}
+
+// MediaLibrary keeps track of the downloaded media files, so the database knows which videos are actually on disk.
+type MediaLibrary struct {
+	db *sql.DB
+}
+
+// NewMediaLibrary creates a media library.
+func NewMediaLibrary(db *sql.DB) *MediaLibrary {
+	return &MediaLibrary{db: db}
+}
+
+// mediaInfo is the part of an info.json file needed to find the media file.
+type mediaInfo struct {
+	YouTubeID string `json:"id"`
+	Type      string `json:"_type"`
+	Filename  string `json:"_filename"`
+}
+
+// MediaProblem is a media file that is no longer the same as when it was scanned.
+type MediaProblem struct {
+	YouTubeID string
+	Path      string
+	Status    string
+}
+
+// Scan recursively finds the info.json files in dirs and records the media file next to each one. Videos with a media
+// file are marked as archived. Files that have the same size and modification time as the last scan are not hashed
+// again. Files that changed since they were first scanned keep what was recorded and are marked as truncated or
+// modified, the same as Verify, unless update is true, which records them as they are now.
+func (ml *MediaLibrary) Scan(ctx context.Context, update bool, dirs ...string) error {
+	var infoFiles []string
+	for _, dir := range dirs {
+		// media files are recorded by absolute path, so it does not matter where verify is run from
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+
+		files, err := findJSONFiles(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			if strings.HasSuffix(f, ".info.json") {
+				infoFiles = append(infoFiles, f)
+			}
+		}
+	}
+
+	progress := progressFrom(ctx)
+	progress.Start("scanning media", int64(len(infoFiles)))
+
+	var sErrs []error
+	found := 0
+	for _, infoFile := range infoFiles {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		progress.Add(1)
+
+		ok, err := ml.scanFile(ctx, infoFile, update)
+		if err != nil {
+			sErrs = append(sErrs, fmt.Errorf("%s: %w", infoFile, err))
+		}
+		if ok {
+			found++
+		}
+	}
+	progress.Finish()
+
+	progress.Logf("found %d media files for %d info.json files, %d failed", found, len(infoFiles), len(sErrs))
+
+	return errors.Join(sErrs...)
+}
+
+// scanFile records the media file for an info.json file. It returns false if there is no media file.
+func (ml *MediaLibrary) scanFile(ctx context.Context, infoFile string, update bool) (bool, error) {
+	data, err := os.ReadFile(infoFile)
+	if err != nil {
+		return false, err
+	}
+
+	var info mediaInfo
+	err = json.Unmarshal(data, &info)
+	if err != nil {
+		return false, err
+	}
+
+	if info.Type != "video" {
+		return false, nil
+	}
+
+	mediaFile := findMediaFile(infoFile, info.Filename)
+	if mediaFile == "" {
+		return false, nil
+	}
+
+	stat, err := os.Stat(mediaFile)
+	if err != nil {
+		return false, err
+	}
+
+	var videoID int64
+	err = ml.db.QueryRowContext(ctx, "SELECT id FROM videos WHERE youtube_id = ?", info.YouTubeID).Scan(&videoID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("video has not been imported: video ID = \"%s\"", info.YouTubeID)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var size, modifiedAt int64
+	var checksum string
+	err = ml.db.QueryRowContext(ctx, "SELECT size, modified_at, checksum FROM media_files WHERE path = ?", mediaFile).
+		Scan(&size, &modifiedAt, &checksum)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	scanned := err == nil
+	changed := scanned && (size != stat.Size() || modifiedAt != stat.ModTime().Unix())
+
+	now := time.Now().Unix()
+	if changed && !update {
+		// keep what was recorded, so a file that was cut short or corrupted is not accepted as the new baseline
+		status := MediaModified
+		if stat.Size() < size {
+			status = MediaTruncated
+		}
+
+		_, err = ml.db.ExecContext(ctx, "UPDATE media_files SET video_id = ?, scanned_at = ?, status = ?, verified_at = ? WHERE path = ?",
+			videoID, now, status, now, mediaFile)
+		if err != nil {
+			return false, fmt.Errorf("unable to save media file: %w", err)
+		}
+
+		err = ml.updateArchived(ctx, videoID)
+		if err != nil {
+			return false, fmt.Errorf("unable to update video: %w", err)
+		}
+
+		return true, nil
+	}
+
+	if !scanned || changed {
+		checksum, err = fileChecksum(mediaFile)
+		if err != nil {
+			return false, fmt.Errorf("unable to hash media file: %w", err)
+		}
+	}
+
+	_, err = ml.db.ExecContext(ctx, `INSERT INTO media_files(video_id, path, size, modified_at, checksum, scanned_at, status, verified_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			video_id = excluded.video_id,
+			size = excluded.size,
+			modified_at = excluded.modified_at,
+			checksum = excluded.checksum,
+			scanned_at = excluded.scanned_at,
+			status = excluded.status,
+			verified_at = excluded.verified_at`,
+		videoID, mediaFile, stat.Size(), stat.ModTime().Unix(), checksum, now, MediaOK, now)
+	if err != nil {
+		return false, fmt.Errorf("unable to save media file: %w", err)
+	}
+
+	_, err = ml.db.ExecContext(ctx, "UPDATE videos SET is_archived = TRUE WHERE id = ?", videoID)
+	if err != nil {
+		return false, fmt.Errorf("unable to mark video as archived: %w", err)
+	}
+
+	return true, nil
+}
+
+// Verify checks that every media file is still the same as when it was scanned. Only the size and modification time
+// are compared unless checksums is true, which reads every file. Videos are marked as not archived when their media
+// files are missing or truncated, and archived again when they come back.
+func (ml *MediaLibrary) Verify(ctx context.Context, checksums bool) ([]MediaProblem, error) {
+	type mediaFile struct {
+		id         int64
+		videoID    int64
+		youtubeID  string
+		path       string
+		size       int64
+		modifiedAt int64
+		checksum   string
+	}
+
+	rows, err := ml.db.QueryContext(ctx, `SELECT media_files.id, video_id, videos.youtube_id, path, size, modified_at, checksum
+		FROM media_files
+		JOIN videos ON videos.id = media_files.video_id
+		ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []mediaFile
+	for rows.Next() {
+		var f mediaFile
+		if err := rows.Scan(&f.id, &f.videoID, &f.youtubeID, &f.path, &f.size, &f.modifiedAt, &f.checksum); err != nil {
+			return nil, err
+		}
+
+		files = append(files, f)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	progress := progressFrom(ctx)
+	progress.Start("verifying media", int64(len(files)))
+
+	var problems []MediaProblem
+	var vErrs []error
+	for _, f := range files {
+		if ctx.Err() != nil {
+			return problems, ctx.Err()
+		}
+		progress.Add(1)
+
+		status, err := verifyMediaFile(f.path, f.size, f.modifiedAt, f.checksum, checksums)
+		if err != nil {
+			vErrs = append(vErrs, fmt.Errorf("%s: %w", f.path, err))
+			continue
+		}
+
+		if status != MediaOK {
+			problems = append(problems, MediaProblem{YouTubeID: f.youtubeID, Path: f.path, Status: status})
+		}
+
+		_, err = ml.db.ExecContext(ctx, "UPDATE media_files SET status = ?, verified_at = ? WHERE id = ?", status, time.Now().Unix(), f.id)
+		if err != nil {
+			vErrs = append(vErrs, fmt.Errorf("%s: unable to save status: %w", f.path, err))
+			continue
+		}
+
+		err = ml.updateArchived(ctx, f.videoID)
+		if err != nil {
+			vErrs = append(vErrs, fmt.Errorf("%s: unable to update video: %w", f.path, err))
+		}
+	}
+	progress.Finish()
+
+	return problems, errors.Join(vErrs...)
+}
+
+// updateArchived marks the video as archived as long as one of its files is usable.
+func (ml *MediaLibrary) updateArchived(ctx context.Context, videoID int64) error {
+	_, err := ml.db.ExecContext(ctx, `UPDATE videos SET is_archived = EXISTS(
+		SELECT 1 FROM media_files WHERE video_id = videos.id AND status IN (?, ?)
+	) WHERE id = ?`, MediaOK, MediaModified, videoID)
+
+	return err
+}
+
+// verifyMediaFile compares a media file to what was recorded when it was scanned.
+func verifyMediaFile(path string, size int64, modifiedAt int64, checksum string, checksums bool) (string, error) {
+	stat, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return MediaMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case stat.Size() < size:
+		return MediaTruncated, nil
+	case stat.Size() != size || stat.ModTime().Unix() != modifiedAt:
+		return MediaModified, nil
+	case !checksums:
+		return MediaOK, nil
+	}
+
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return "", err
+	}
+
+	if sum != checksum {
+		return MediaModified, nil
+	}
+
+	return MediaOK, nil
+}
+
+// findMediaFile finds the media file that goes with an info.json file. yt-dlp records the file name it used, but the
+// extension changes when formats are merged, so files with the same name as the info.json file are checked too.
+func findMediaFile(infoFile string, filename string) string {
+	dir := filepath.Dir(infoFile)
+
+	if filename != "" {
+		candidate := filepath.Join(dir, filepath.Base(filename))
+		if isMediaFile(candidate) {
+			return candidate
+		}
+	}
+
+	base := strings.TrimSuffix(infoFile, ".info.json")
+	for _, ext := range mediaExtensions {
+		if isMediaFile(base + ext) {
+			return base + ext
+		}
+	}
+
+	return ""
+}
+
+func isMediaFile(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// fileChecksum is the SHA-256 of the file contents.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+// DefaultSecretFile is the OAuth client secret downloaded from the Google Cloud console.
+const DefaultSecretFile = "client_secret.json"
+
+// DefaultTokenFile is where the OAuth token is saved if no other file is given. This is the same file used by the
+// YouTube Data API Go quickstart.
+var DefaultTokenFile = defaultTokenFile()
+
+// ErrNotAuthorized is returned by LoadYouTubeService when there is no saved OAuth token.
+var ErrNotAuthorized = errors.New("not authorized with the YouTube Data API, run the auth command first")
+
+// NewYouTubeService creates a YouTube Data API client using a previously saved OAuth token. If there is no saved
+// token the user is asked to authorize the app.
+func NewYouTubeService(ctx context.Context, secretFile string, tokenFile string) (*youtube.Service, error) {
+	service, err := LoadYouTubeService(ctx, secretFile, tokenFile)
+	if !errors.Is(err, ErrNotAuthorized) {
+		return service, err
+	}
+
+	_, err = Authorize(ctx, secretFile, tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadYouTubeService(ctx, secretFile, tokenFile)
+}
+
+// LoadYouTubeService creates a YouTube Data API client using a previously saved OAuth token, without asking the user
+// to authorize the app. It is used where nobody can type in an authorization code, like in the server.
+func LoadYouTubeService(ctx context.Context, secretFile string, tokenFile string) (*youtube.Service, error) {
+	config, err := oauthConfig(secretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := tokenFromFile(tokenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotAuthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token file: file = \"%s\": %w", tokenFile, err)
+	}
+
+	return youtube.NewService(ctx, option.WithHTTPClient(config.Client(ctx, tok)))
+}
+
+// Authorize asks the user to give access to the YouTube Data API and saves the token.
+func Authorize(ctx context.Context, secretFile string, tokenFile string) (*oauth2.Token, error) {
+	config, err := oauthConfig(secretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+	fmt.Printf("Go to the following link in your browser then type the "+
+		"authorization code: \n%v\n", authURL)
+
+	var code string
+	if _, err := fmt.Scan(&code); err != nil {
+		return nil, fmt.Errorf("unable to read authorization code: %w", err)
+	}
+
+	tok, err := config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve token from web: %w", err)
+	}
+
+	err = saveToken(tokenFile, tok)
+	if err != nil {
+		return nil, err
+	}
+
+	return tok, nil
+}
+
+func oauthConfig(secretFile string) (*oauth2.Config, error) {
+	b, err := os.ReadFile(secretFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client secret file: file = \"%s\": %w", secretFile, err)
+	}
+
+	// If modifying these scopes, delete your previously saved credentials
+	config, err := google.ConfigFromJSON(b, youtube.YoutubeReadonlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+
+	return config, nil
+}
+
+// tokenFromFile retrieves a Token from a given file path.
+// It returns the retrieved Token and any read error encountered.
+func tokenFromFile(file string) (*oauth2.Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(t)
+	return t, err
+}
+
+// saveToken uses a file path to create a file and store the
+// token in it.
+func saveToken(file string, token *oauth2.Token) error {
+	fmt.Printf("Saving credential file to: %s\n", file)
+
+	err := os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return fmt.Errorf("unable to create credential directory: %w", err)
+	}
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(token)
+}
+
+func defaultTokenFile() string {
+	name := url.QueryEscape("youtube-go-quickstart.json")
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return name
+	}
+
+	return filepath.Join(home, ".credentials", name)
+}
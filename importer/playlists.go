@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// YTDLPPlaylist is the playlist metadata from a yt-dlp info.json file.
+type YTDLPPlaylist struct {
+	YouTubeID    string               `json:"id"`
+	Type         string               `json:"_type"`
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	ChannelID    string               `json:"channel_id"`
+	WebpageURL   string               `json:"webpage_url"`
+	Availability string               `json:"availability"`
+	ViewCount    *int64               `json:"view_count"`
+	ModifiedDate string               `json:"modified_date"` // YYYYMMDD
+	Entries      []YTDLPPlaylistEntry `json:"entries"`
+}
+
+// YTDLPPlaylistEntry is a video in a playlist. Only the fields that are always included are used, since entries can
+// be full videos or just the ID and title depending on how yt-dlp was run.
+type YTDLPPlaylistEntry struct {
+	YouTubeID string `json:"id"`
+	Title     string `json:"title"`
+}
+
+// saveYTDLPPlaylist saves a playlist and the videos in it. The videos are replaced if the playlist was already saved.
+func saveYTDLPPlaylist(ctx context.Context, tx DBTX, p *YTDLPPlaylist) error {
+	var channelID *int64
+	if p.ChannelID != "" {
+		var id int64
+		err := tx.QueryRowContext(ctx, "SELECT id FROM channels WHERE youtube_id = ?", p.ChannelID).Scan(&id)
+		switch {
+		case err == nil:
+			channelID = &id
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf(`%s: missing channel: channel ID = "%s": unexpected errror %w`, p.YouTubeID, p.ChannelID, err)
+		}
+	}
+
+	var modifiedAt *int64
+	if t, err := time.Parse("20060102", p.ModifiedDate); err == nil {
+		ts := t.Unix()
+		modifiedAt = &ts
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO playlists(youtube_id, channel_id, title, description, webpage_url, availability, view_count, modified_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(youtube_id) DO UPDATE SET
+			channel_id = excluded.channel_id,
+			title = excluded.title,
+			description = excluded.description,
+			webpage_url = excluded.webpage_url,
+			availability = excluded.availability,
+			view_count = excluded.view_count,
+			modified_at = excluded.modified_at`,
+		p.YouTubeID, channelID, p.Title, p.Description, p.WebpageURL, p.Availability, p.ViewCount, modifiedAt)
+	if err != nil {
+		return fmt.Errorf("%s: failed to save playlist: %w", p.YouTubeID, err)
+	}
+
+	var playlistID int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM playlists WHERE youtube_id = ?", p.YouTubeID).Scan(&playlistID)
+	if err != nil {
+		return fmt.Errorf("%s: unable to get database ID: %w", p.YouTubeID, err)
+	}
+
+	if p.Entries == nil {
+		// keep the videos from an earlier import
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM playlist_videos WHERE playlist_id = ?", playlistID)
+	if err != nil {
+		return fmt.Errorf("%s: unable to clear playlist videos: %w", p.YouTubeID, err)
+	}
+
+	for i, e := range p.Entries {
+		if e.YouTubeID == "" {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO playlist_videos(playlist_id, position, video_youtube_id, title) VALUES(?, ?, ?, ?)",
+			playlistID, i, e.YouTubeID, e.Title)
+		if err != nil {
+			return fmt.Errorf("%s: unable to save playlist video: %s : %w", p.YouTubeID, e.YouTubeID, err)
+		}
+	}
+
+	return nil
+}
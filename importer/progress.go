@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Progress receives updates from a running import. The command line prints them, while the server streams them to
+// the browser. Imports use the Progress set with WithProgress, or print to the console if there is none.
+type Progress interface {
+	// Start begins a step of the import with total items, or -1 if the number of items is not known.
+	Start(description string, total int64)
+	// Add marks n items of the current step as done.
+	Add(n int)
+	// Finish ends the current step.
+	Finish()
+	// Error reports an item that could not be imported. The import keeps going.
+	Error(err error)
+	// Logf reports a line of output, such as a summary of what was imported.
+	Logf(format string, args ...any)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context that sends the progress of imports to p.
+func WithProgress(ctx context.Context, p Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progressFrom gets the Progress for an import. Each call without a Progress in the context creates a new console
+// progress bar, so imports should only call it once.
+func progressFrom(ctx context.Context) Progress {
+	if p, ok := ctx.Value(progressKey{}).(Progress); ok {
+		return p
+	}
+
+	return &consoleProgress{}
+}
+
+// consoleProgress prints to stdout and shows a progress bar for each step.
+type consoleProgress struct {
+	bar *progressbar.ProgressBar
+}
+
+func (cp *consoleProgress) Start(description string, total int64) {
+	cp.Finish()
+	cp.bar = progressbar.Default(total, description)
+}
+
+func (cp *consoleProgress) Add(n int) {
+	if cp.bar != nil {
+		_ = cp.bar.Add(n)
+	}
+}
+
+func (cp *consoleProgress) Finish() {
+	if cp.bar != nil {
+		_ = cp.bar.Finish()
+		cp.bar = nil
+	}
+}
+
+func (cp *consoleProgress) Error(err error) {
+	cp.Logf("...%s", err)
+}
+
+func (cp *consoleProgress) Logf(format string, args ...any) {
+	// the bar is drawn again on the next update
+	if cp.bar != nil {
+		_ = cp.bar.Clear()
+	}
+
+	fmt.Printf(format+"\n", args...)
+}
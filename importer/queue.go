@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Queue keeps track of items that still need to be imported, so an import that stops early can be resumed.
+type Queue struct {
+	db   *sql.DB
+	kind string
+}
+
+// NewQueue creates a queue for the given kind of item (ex. "channel").
+func NewQueue(db *sql.DB, kind string) *Queue {
+	return &Queue{db: db, kind: kind}
+}
+
+// Push adds items to the queue. Items that are already queued are ignored.
+func (q *Queue) Push(ctx context.Context, items ...string) error {
+	now := time.Now().Unix()
+
+	for _, item := range items {
+		_, err := q.db.ExecContext(ctx, "INSERT INTO import_queue(kind, item, queued_at) VALUES(?, ?, ?)", q.kind, item, now)
+		if err != nil {
+			return fmt.Errorf("could not queue item: %s = \"%s\": %w", q.kind, item, err)
+		}
+	}
+
+	return nil
+}
+
+// Pending lists the queued items in the order they were added.
+func (q *Queue) Pending(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT item FROM import_queue WHERE kind = ? ORDER BY id", q.kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []string
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Done removes items from the queue once they have been imported.
+func (q *Queue) Done(ctx context.Context, items ...string) error {
+	for _, item := range items {
+		_, err := q.db.ExecContext(ctx, "DELETE FROM import_queue WHERE kind = ? AND item = ?", q.kind, item)
+		if err != nil {
+			return fmt.Errorf("could not remove item from queue: %s = \"%s\": %w", q.kind, item, err)
+		}
+	}
+
+	return nil
+}
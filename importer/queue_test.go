@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+// testProgress collects the output of an import instead of printing it.
+type testProgress struct {
+	lines  []string
+	errors []error
+}
+
+func (tp *testProgress) Start(description string, total int64) {}
+func (tp *testProgress) Add(n int)                             {}
+func (tp *testProgress) Finish()                               {}
+func (tp *testProgress) Error(err error)                       { tp.errors = append(tp.errors, err) }
+func (tp *testProgress) Logf(format string, args ...any) {
+	tp.lines = append(tp.lines, fmt.Sprintf(format, args...))
+}
+
+// newTestYouTube creates a YouTube client that sends requests to a stand-in for the YouTube Data API. Nothing is
+// cached, so every call is made and counted against the quota.
+func newTestYouTube(t *testing.T, api http.Handler, quota *Quota) *YouTube {
+	t.Helper()
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	service, err := youtube.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewYouTube(service, NewNullCache(), quota)
+}
+
+// testChannelsAPI answers channels.list with a channel for every requested ID.
+func testChannelsAPI(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/youtube/v3/channels" {
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+
+		response := youtube.ChannelListResponse{}
+		var ids []string
+		for _, id := range r.URL.Query()["id"] {
+			ids = append(ids, strings.Split(id, ",")...)
+		}
+
+		for _, id := range ids {
+			response.Items = append(response.Items, &youtube.Channel{
+				Id:               id,
+				Snippet:          &youtube.ChannelSnippet{Title: "Channel " + id},
+				BrandingSettings: &youtube.ChannelBrandingSettings{Channel: &youtube.ChannelSettings{}},
+				Statistics:       &youtube.ChannelStatistics{},
+				ContentDetails:   &youtube.ChannelContentDetails{RelatedPlaylists: &youtube.ChannelContentDetailsRelatedPlaylists{}},
+			})
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+// TestImportChannelsResume checks that channels left over when the quota runs out are imported by the next run.
+func TestImportChannelsResume(t *testing.T) {
+	ctx := WithProgress(context.Background(), &testProgress{})
+	db := openTestDB(t)
+
+	// one more channel than fits in a request, so the second request goes over the budget
+	channelIDs := make([]string, MaxIDsPerRequest+1)
+	for i := range channelIDs {
+		channelIDs[i] = fmt.Sprintf("UC%022d", i)
+	}
+
+	yi := NewYouTubeImporter(db, newTestYouTube(t, testChannelsAPI(t), newTestQuota(t, db, 1)))
+	err := yi.ImportChannels(ctx, channelIDs)
+	if !IsQuotaError(err) {
+		t.Fatalf("ImportChannels returned %v, want a quota error", err)
+	}
+
+	pending, err := NewQueue(db, "channel").Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0] != channelIDs[MaxIDsPerRequest] {
+		t.Fatalf("queue has %v, want the last channel", pending)
+	}
+
+	// the next run has a bigger budget, but the unit used by the first run still counts
+	yi = NewYouTubeImporter(db, newTestYouTube(t, testChannelsAPI(t), newTestQuota(t, db, 2)))
+	resumed, err := yi.Resume(ctx)
+	if err != nil || !resumed {
+		t.Fatalf("Resume returned %t, %v", resumed, err)
+	}
+
+	pending, err = NewQueue(db, "channel").Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("queue still has %v after resuming", pending)
+	}
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM channels").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(channelIDs) {
+		t.Errorf("%d channels were saved, want %d", count, len(channelIDs))
+	}
+
+	resumed, err = yi.Resume(ctx)
+	if err != nil || resumed {
+		t.Errorf("Resume with an empty queue returned %t, %v", resumed, err)
+	}
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// DefaultQuotaBudget is the daily quota given to new YouTube Data API projects.
+const DefaultQuotaBudget = 10000
+
+// quotaTimezone is where the YouTube Data API quota resets at midnight.
+const quotaTimezone = "America/Los_Angeles"
+
+// QuotaCosts is the number of quota units used by each YouTube Data API call.
+// see: https://developers.google.com/youtube/v3/determine_quota_cost
+var QuotaCosts = map[string]int{
+	"channels.list":        1,
+	"playlistItems.list":   1,
+	"subscriptions.list":   1,
+	"videoCategories.list": 1,
+	"videos.list":          1,
+	"search.list":          100,
+}
+
+// ErrQuotaExceeded is returned when an API call would go over the quota budget, or YouTube tells us we are out of
+// quota.
+var ErrQuotaExceeded = errors.New("quota budget exceeded")
+
+// Quota keeps a ledger of the YouTube Data API quota used for the current day, so that imports can stop before they
+// run out of quota instead of failing part way through a request.
+type Quota struct {
+	db     *sql.DB
+	budget int
+
+	mu   sync.Mutex
+	day  string
+	used int
+}
+
+// NewQuota creates a quota ledger with the given daily budget. The units already used today are loaded from the
+// database, so separate runs share the same budget.
+func NewQuota(ctx context.Context, db *sql.DB, budget int) (*Quota, error) {
+	q := &Quota{db: db, budget: budget}
+
+	err := q.load(ctx, quotaDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Spend records a call to the given API method. If the call would go over the budget nothing is recorded and
+// ErrQuotaExceeded is returned, so the call should not be made.
+func (q *Quota) Spend(ctx context.Context, method string) error {
+	cost, ok := QuotaCosts[method]
+	if !ok {
+		return fmt.Errorf("unknown quota cost: method = \"%s\"", method)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	day := quotaDay(time.Now())
+	if day != q.day {
+		// quota was reset while we were running
+		err := q.load(ctx, day)
+		if err != nil {
+			return err
+		}
+	}
+
+	if q.used+cost > q.budget {
+		return fmt.Errorf("%s: %d of %d units used: %w", method, q.used, q.budget, ErrQuotaExceeded)
+	}
+
+	_, err := q.db.ExecContext(ctx, `INSERT INTO quota_usage(day, method, calls, units) VALUES(?, ?, 1, ?)
+		ON CONFLICT(day, method) DO UPDATE SET calls = calls + 1, units = units + excluded.units`,
+		q.day, method, cost)
+	if err != nil {
+		return fmt.Errorf("could not record quota usage: method = \"%s\": %w", method, err)
+	}
+
+	q.used += cost
+
+	return nil
+}
+
+// Used is the number of units used today.
+func (q *Quota) Used() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.used
+}
+
+// Remaining is the number of units left in today's budget.
+func (q *Quota) Remaining() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return max(q.budget-q.used, 0)
+}
+
+func (q *Quota) load(ctx context.Context, day string) error {
+	var used int
+
+	err := q.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(units), 0) FROM quota_usage WHERE day = ?", day).
+		Scan(&used)
+	if err != nil {
+		return fmt.Errorf("could not load quota usage: day = \"%s\": %w", day, err)
+	}
+
+	q.day = day
+	q.used = used
+
+	return nil
+}
+
+// IsQuotaError checks if err is either from going over our own budget, or YouTube rejecting a request because the
+// project is out of quota.
+func IsQuotaError(err error) bool {
+	if errors.Is(err, ErrQuotaExceeded) {
+		return true
+	}
+
+	var gErr *googleapi.Error
+	if !errors.As(err, &gErr) || gErr.Code != http.StatusForbidden {
+		return false
+	}
+
+	for _, e := range gErr.Errors {
+		if e.Reason == "quotaExceeded" || e.Reason == "dailyLimitExceeded" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quotaDay is the quota day that t falls on.
+func quotaDay(t time.Time) string {
+	loc, err := time.LoadLocation(quotaTimezone)
+	if err != nil {
+		// no timezone database available, so ignore daylight saving time
+		loc = time.FixedZone("PST", -8*60*60)
+	}
+
+	return t.In(loc).Format(time.DateOnly)
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+// TestQuotaBudget checks that calls stop at the budget, and that the units used are shared with later runs on the same
+// day.
+func TestQuotaBudget(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	quota := newTestQuota(t, db, 2)
+
+	for range 2 {
+		err := quota.Spend(ctx, "channels.list")
+		if err != nil {
+			t.Fatalf("Spend: %s", err)
+		}
+	}
+
+	err := quota.Spend(ctx, "videos.list")
+	if !errors.Is(err, ErrQuotaExceeded) || !IsQuotaError(err) {
+		t.Errorf("Spend over the budget returned %v, want ErrQuotaExceeded", err)
+	}
+
+	if quota.Used() != 2 || quota.Remaining() != 0 {
+		t.Errorf("Used = %d, Remaining = %d, want 2 and 0", quota.Used(), quota.Remaining())
+	}
+
+	err = quota.Spend(ctx, "unknown.list")
+	if err == nil || IsQuotaError(err) {
+		t.Errorf("Spend with an unknown method returned %v", err)
+	}
+
+	next := newTestQuota(t, db, 5)
+	if next.Used() != 2 || next.Remaining() != 3 {
+		t.Errorf("next run: Used = %d, Remaining = %d, want 2 and 3", next.Used(), next.Remaining())
+	}
+
+	var calls, units int
+	err = db.QueryRow("SELECT calls, units FROM quota_usage WHERE method = 'channels.list'").Scan(&calls, &units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || units != 2 {
+		t.Errorf("ledger has %d calls and %d units, want 2 and 2", calls, units)
+	}
+}
+
+// TestIsQuotaError checks that YouTube running out of quota is treated the same as going over our own budget.
+func TestIsQuotaError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, true},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, false},
+		{&googleapi.Error{Code: http.StatusNotFound}, false},
+		{errors.New("network error"), false},
+	}
+
+	for _, test := range tests {
+		if got := IsQuotaError(test.err); got != test.want {
+			t.Errorf("IsQuotaError(%v) = %t, want %t", test.err, got, test.want)
+		}
+	}
+}
+
+func newTestQuota(t *testing.T, db *sql.DB, budget int) *Quota {
+	t.Helper()
+
+	quota, err := NewQuota(context.Background(), db, budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return quota
+}
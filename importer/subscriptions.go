@@ -0,0 +1,321 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// subscription file formats
+const (
+	FormatCSV       = "csv"
+	FormatNewPipe   = "newpipe"
+	FormatFreeTube  = "freetube"
+	FormatInvidious = "invidious"
+	FormatOPML      = "opml"
+)
+
+// channel reference kinds
+const (
+	ChannelRefID       = "id"
+	ChannelRefHandle   = "handle"
+	ChannelRefCustom   = "custom"
+	ChannelRefUsername = "username"
+)
+
+// channelIDPattern matches YouTube channel IDs.
+var channelIDPattern = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+
+// ChannelReference is one of the ways a channel can be referred to. Only IDs can be looked up directly, the rest have
+// to be resolved to an ID first.
+type ChannelReference struct {
+	Kind  string
+	Value string // without the @ for handles
+}
+
+// newPipeYouTubeService is the NewPipe service ID for YouTube. NewPipe also supports other sites.
+const newPipeYouTubeService = 0
+
+// newPipeExport is the subscriptions export from NewPipe.
+type newPipeExport struct {
+	Subscriptions []struct {
+		ServiceID int    `json:"service_id"`
+		URL       string `json:"url"`
+		Name      string `json:"name"`
+	} `json:"subscriptions"`
+}
+
+// freeTubeProfile is a FreeTube profile. FreeTube's profiles .db file has one profile per line, and the export is a
+// single profile.
+type freeTubeProfile struct {
+	Subscriptions []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"subscriptions"`
+}
+
+// invidiousExport is the data export from Invidious, which only has the channel IDs.
+type invidiousExport struct {
+	Subscriptions []string `json:"subscriptions"`
+}
+
+// opmlOutline is an outline in an OPML feed list. Subscriptions are usually nested in a single top level outline.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ReadSubscriptions reads channel IDs from a subscriptions export. The format is detected from the contents of the
+// file. Takeout-style CSV, NewPipe JSON, FreeTube .db and JSON, Invidious JSON, and OPML feed lists are supported.
+// Channels given by handle, custom URL, or username are returned as is, see ParseChannelReference.
+func ReadSubscriptions(file string) ([]string, error) {
+	if len(file) == 0 {
+		// no file given
+		return nil, errors.New("no input file given")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	format := DetectSubscriptionsFormat(data)
+
+	var ids []string
+	switch format {
+	case FormatNewPipe:
+		ids, err = readNewPipe(data)
+	case FormatFreeTube:
+		ids, err = readFreeTube(data)
+	case FormatInvidious:
+		ids, err = readInvidious(data)
+	case FormatOPML:
+		ids, err = readOPML(data)
+	default:
+		return ReadChannelsCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s subscriptions: %w", format, err)
+	}
+
+	return uniqueIDs(ids), nil
+}
+
+// DetectSubscriptionsFormat guesses the format of a subscriptions export. Anything that is not recognized is treated
+// as CSV.
+func DetectSubscriptionsFormat(data []byte) string {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("<")):
+		return FormatOPML
+	case !bytes.HasPrefix(data, []byte("{")):
+		return FormatCSV
+	}
+
+	var export struct {
+		Subscriptions []json.RawMessage `json:"subscriptions"`
+	}
+
+	// FreeTube's .db file has more than one JSON object, so only the first one is decoded
+	err := json.NewDecoder(bytes.NewReader(data)).Decode(&export)
+	if err != nil || len(export.Subscriptions) == 0 {
+		// FreeTube profiles with no subscriptions still look like FreeTube
+		if bytes.Contains(data, []byte(`"bgColor"`)) {
+			return FormatFreeTube
+		}
+		return FormatCSV
+	}
+
+	first := bytes.TrimSpace(export.Subscriptions[0])
+	switch {
+	case bytes.HasPrefix(first, []byte(`"`)):
+		return FormatInvidious
+	case bytes.Contains(first, []byte(`"service_id"`)):
+		return FormatNewPipe
+	default:
+		return FormatFreeTube
+	}
+}
+
+func readNewPipe(data []byte) ([]string, error) {
+	var export newPipeExport
+	err := json.Unmarshal(data, &export)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(export.Subscriptions))
+	for _, s := range export.Subscriptions {
+		if s.ServiceID != newPipeYouTubeService {
+			continue
+		}
+
+		if id := ChannelIDFromURL(s.URL); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func readFreeTube(data []byte) ([]string, error) {
+	var ids []string
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var profile freeTubeProfile
+		err := dec.Decode(&profile)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// every profile is a subset of the "All Channels" profile, duplicates are removed later
+		for _, s := range profile.Subscriptions {
+			if s.ID != "" {
+				ids = append(ids, s.ID)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func readInvidious(data []byte) ([]string, error) {
+	var export invidiousExport
+	err := json.Unmarshal(data, &export)
+	if err != nil {
+		return nil, err
+	}
+
+	return export.Subscriptions, nil
+}
+
+func readOPML(data []byte) ([]string, error) {
+	var opml struct {
+		Body struct {
+			Outlines []opmlOutline `xml:"outline"`
+		} `xml:"body"`
+	}
+
+	err := xml.Unmarshal(data, &opml)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	var walk func([]opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, o := range outlines {
+			if ref := channelReference(o.XMLURL); ref != "" {
+				ids = append(ids, ref)
+			}
+			walk(o.Outlines)
+		}
+	}
+	walk(opml.Body.Outlines)
+
+	return ids, nil
+}
+
+// ChannelIDFromURL gets the channel ID from a channel URL or feed URL. This works for YouTube URLs like /channel/ID
+// and /feeds/videos.xml?channel_id=ID, and the same paths on Invidious instances (/channel/ID and /feed/channel/ID).
+func ChannelIDFromURL(channelURL string) string {
+	u, err := url.Parse(strings.TrimSpace(channelURL))
+	if err != nil {
+		return ""
+	}
+
+	if id := u.Query().Get("channel_id"); id != "" {
+		return id
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "channel" && i+1 < len(segments) {
+			return segments[i+1]
+		}
+	}
+
+	return ""
+}
+
+// ParseChannelReference works out how a channel is being referred to. Channel IDs, @handles, and channel URLs using any
+// of /channel/ID, /@handle, /c/name, or /user/name are recognized. URLs do not need the scheme.
+func ParseChannelReference(s string) (ChannelReference, bool) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case strings.HasPrefix(s, "@"):
+		return ChannelReference{Kind: ChannelRefHandle, Value: s[1:]}, len(s) > 1
+	case channelIDPattern.MatchString(s):
+		return ChannelReference{Kind: ChannelRefID, Value: s}, true
+	case !strings.Contains(s, "/"):
+		return ChannelReference{}, false
+	}
+
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	if id := ChannelIDFromURL(s); id != "" {
+		return ChannelReference{Kind: ChannelRefID, Value: id}, true
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return ChannelReference{}, false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(segments[0], "@") && len(segments[0]) > 1:
+		return ChannelReference{Kind: ChannelRefHandle, Value: segments[0][1:]}, true
+	case len(segments) > 1 && segments[0] == "c" && segments[1] != "":
+		return ChannelReference{Kind: ChannelRefCustom, Value: segments[1]}, true
+	case len(segments) > 1 && segments[0] == "user" && segments[1] != "":
+		return ChannelReference{Kind: ChannelRefUsername, Value: segments[1]}, true
+	}
+
+	return ChannelReference{}, false
+}
+
+// channelReference gets the channel ID from a URL, or keeps the URL if it refers to the channel by handle, custom URL,
+// or username. Those are resolved to IDs when the channels are imported.
+func channelReference(channelURL string) string {
+	ref, ok := ParseChannelReference(channelURL)
+	switch {
+	case !ok:
+		return ""
+	case ref.Kind == ChannelRefID:
+		return ref.Value
+	default:
+		return strings.TrimSpace(channelURL)
+	}
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestReadSubscriptions checks that each kind of subscriptions export is detected and read.
+func TestReadSubscriptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format string
+		want   []string
+	}{
+		{
+			name: "takeout.csv",
+			data: "Channel Id,Channel Url,Channel Title\n" +
+				"UC0000000000000000000001,http://www.youtube.com/channel/UC0000000000000000000001,First\n",
+			format: FormatCSV,
+			want:   []string{"UC0000000000000000000001"},
+		},
+		{
+			name: "newpipe.json",
+			data: `{"app_version": "0.27.0", "subscriptions": [
+				{"service_id": 0, "url": "https://www.youtube.com/channel/UC0000000000000000000001", "name": "First"},
+				{"service_id": 1, "url": "https://soundcloud.com/someone", "name": "Not YouTube"},
+				{"service_id": 0, "url": "https://www.youtube.com/channel/UC0000000000000000000002", "name": "Second"}
+			]}`,
+			format: FormatNewPipe,
+			want:   []string{"UC0000000000000000000001", "UC0000000000000000000002"},
+		},
+		{
+			// the .db file has one profile per line, and every profile is a subset of "All Channels"
+			name: "profiles.db",
+			data: `{"name":"All Channels","bgColor":"#000000","subscriptions":[{"id":"UC0000000000000000000001","name":"First"},{"id":"UC0000000000000000000002","name":"Second"}],"_id":"allChannels"}
+{"name":"Music","bgColor":"#FFFFFF","subscriptions":[{"id":"UC0000000000000000000002","name":"Second"}],"_id":"music"}`,
+			format: FormatFreeTube,
+			want:   []string{"UC0000000000000000000001", "UC0000000000000000000002"},
+		},
+		{
+			name:   "invidious.json",
+			data:   `{"subscriptions": ["UC0000000000000000000001"], "watch_history": [], "preferences": {}}`,
+			format: FormatInvidious,
+			want:   []string{"UC0000000000000000000001"},
+		},
+		{
+			name: "feeds.opml",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.1">
+  <body>
+    <outline text="YouTube Subscriptions" title="YouTube Subscriptions">
+      <outline text="First" type="rss" xmlUrl="https://www.youtube.com/feeds/videos.xml?channel_id=UC0000000000000000000001"/>
+      <outline text="Invidious" type="rss" xmlUrl="https://invidious.example.com/feed/channel/UC0000000000000000000002"/>
+      <outline text="Handle" type="rss" xmlUrl="https://www.youtube.com/@handle"/>
+      <outline text="Not YouTube" type="rss" xmlUrl="https://example.com/feed.xml"/>
+    </outline>
+  </body>
+</opml>`,
+			format: FormatOPML,
+			want:   []string{"UC0000000000000000000001", "UC0000000000000000000002", "https://www.youtube.com/@handle"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if format := DetectSubscriptionsFormat([]byte(test.data)); format != test.format {
+				t.Errorf("DetectSubscriptionsFormat = %s, want %s", format, test.format)
+			}
+
+			file := filepath.Join(t.TempDir(), test.name)
+			err := os.WriteFile(file, []byte(test.data), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadSubscriptions(file)
+			if err != nil {
+				t.Fatalf("ReadSubscriptions: %s", err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("ReadSubscriptions = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestDetectEmptyFreeTubeProfile checks that a FreeTube profile without subscriptions is not mistaken for CSV.
+func TestDetectEmptyFreeTubeProfile(t *testing.T) {
+	data := `{"name":"All Channels","bgColor":"#000000","subscriptions":[],"_id":"allChannels"}`
+
+	if format := DetectSubscriptionsFormat([]byte(data)); format != FormatFreeTube {
+		t.Errorf("DetectSubscriptionsFormat = %s, want %s", format, FormatFreeTube)
+	}
+}
+
+// TestParseChannelReference checks each way a channel can be referred to.
+func TestParseChannelReference(t *testing.T) {
+	tests := []struct {
+		s    string
+		want ChannelReference
+		ok   bool
+	}{
+		{"UC0000000000000000000001", ChannelReference{ChannelRefID, "UC0000000000000000000001"}, true},
+		{" @handle ", ChannelReference{ChannelRefHandle, "handle"}, true},
+		{"@", ChannelReference{ChannelRefHandle, ""}, false},
+		{"https://www.youtube.com/channel/UC0000000000000000000001", ChannelReference{ChannelRefID, "UC0000000000000000000001"}, true},
+		{"https://www.youtube.com/feeds/videos.xml?channel_id=UC0000000000000000000001", ChannelReference{ChannelRefID, "UC0000000000000000000001"}, true},
+		{"https://www.youtube.com/@handle/videos", ChannelReference{ChannelRefHandle, "handle"}, true},
+		{"youtube.com/@handle", ChannelReference{ChannelRefHandle, "handle"}, true},
+		{"https://www.youtube.com/c/CustomName", ChannelReference{ChannelRefCustom, "CustomName"}, true},
+		{"https://www.youtube.com/user/username", ChannelReference{ChannelRefUsername, "username"}, true},
+		{"https://www.youtube.com/watch?v=video000001", ChannelReference{}, false},
+		{"Channel Title", ChannelReference{}, false},
+		{"", ChannelReference{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseChannelReference(test.s)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("ParseChannelReference(%q) = %+v, %t, want %+v, %t", test.s, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// TestChannelIDFromURL checks YouTube and Invidious channel and feed URLs.
+func TestChannelIDFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.youtube.com/channel/UC0000000000000000000001":                     "UC0000000000000000000001",
+		"http://www.youtube.com/channel/UC0000000000000000000001/videos":               "UC0000000000000000000001",
+		"https://www.youtube.com/feeds/videos.xml?channel_id=UC0000000000000000000001": "UC0000000000000000000001",
+		"https://invidious.example.com/feed/channel/UC0000000000000000000001":          "UC0000000000000000000001",
+		"https://www.youtube.com/@handle":                                              "",
+		"not a url":                                                                    "",
+	}
+
+	for channelURL, want := range tests {
+		if got := ChannelIDFromURL(channelURL); got != want {
+			t.Errorf("ChannelIDFromURL(%q) = %q, want %q", channelURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// takeoutTimeLayouts are the timestamp formats used by the different versions of the Takeout CSV files.
+var takeoutTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+}
+
+// TakeoutImporter imports the YouTube data from a Google Takeout archive. Everything comes from the archive, so the
+// YouTube Data API is not needed. Run the subscriptions or channels import afterward to fill in the channel details.
+type TakeoutImporter struct {
+	db *sql.DB
+}
+
+// NewTakeoutImporter creates a Google Takeout importer.
+func NewTakeoutImporter(db *sql.DB) *TakeoutImporter {
+	return &TakeoutImporter{db: db}
+}
+
+// takeoutHistoryEntry is an entry in watch-history.json or search-history.json.
+type takeoutHistoryEntry struct {
+	Title     string `json:"title"`
+	TitleURL  string `json:"titleUrl"`
+	Time      string `json:"time"`
+	Subtitles []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"subtitles"`
+	Details []struct {
+		Name string `json:"name"`
+	} `json:"details"`
+}
+
+// takeoutFiles are the files in a Takeout archive that can be imported. The top level folder name is translated into
+// the account's language, so files are found by their folder and file name.
+type takeoutFiles struct {
+	subscriptions *zip.File
+	playlists     *zip.File   // playlists.csv, only in newer archives
+	playlistFiles []*zip.File // one file per playlist
+	watchHistory  *zip.File
+	searchHistory *zip.File
+	htmlHistory   bool
+}
+
+// Import imports the subscriptions, playlists, watch history, and search history from a Takeout zip file. Everything is
+// imported in a single transaction, so an archive that fails to import does not leave partial data behind.
+func (ti *TakeoutImporter) Import(ctx context.Context, file string) error {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("could not open archive: file = \"%s\": %w", file, err)
+	}
+	defer r.Close()
+
+	progress := progressFrom(ctx)
+
+	files := findTakeoutFiles(r.File)
+	if files.htmlHistory && files.watchHistory == nil {
+		progress.Logf("history is in HTML format, export it as JSON to import it")
+	}
+
+	tx, err := ti.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	progress.Start("importing takeout", int64(files.count()))
+	defer progress.Finish()
+
+	if files.subscriptions != nil {
+		count, err := importTakeoutSubscriptions(ctx, tx, files.subscriptions)
+		if err != nil {
+			return fmt.Errorf("unable to import subscriptions: %w", err)
+		}
+		progress.Logf("imported %d subscriptions", count)
+		progress.Add(1)
+	}
+
+	if len(files.playlistFiles) > 0 {
+		count, err := importTakeoutPlaylists(ctx, tx, progress, files.playlists, files.playlistFiles)
+		if err != nil {
+			return fmt.Errorf("unable to import playlists: %w", err)
+		}
+		progress.Logf("imported %d playlists", count)
+		progress.Add(1)
+	}
+
+	if files.watchHistory != nil {
+		count, err := importTakeoutWatchHistory(ctx, tx, files.watchHistory)
+		if err != nil {
+			return fmt.Errorf("unable to import watch history: %w", err)
+		}
+		progress.Logf("imported %d watched videos", count)
+		progress.Add(1)
+	}
+
+	if files.searchHistory != nil {
+		count, err := importTakeoutSearchHistory(ctx, tx, files.searchHistory)
+		if err != nil {
+			return fmt.Errorf("unable to import search history: %w", err)
+		}
+		progress.Logf("imported %d searches", count)
+		progress.Add(1)
+	}
+
+	return tx.Commit()
+}
+
+// count is the number of parts of the archive that will be imported.
+func (tf takeoutFiles) count() int {
+	count := 0
+	for _, found := range []bool{tf.subscriptions != nil, len(tf.playlistFiles) > 0, tf.watchHistory != nil, tf.searchHistory != nil} {
+		if found {
+			count++
+		}
+	}
+
+	return count
+}
+
+func findTakeoutFiles(zipFiles []*zip.File) takeoutFiles {
+	var files takeoutFiles
+
+	for _, f := range zipFiles {
+		dir, name := path.Split(f.Name)
+		dir = path.Base(dir)
+
+		switch {
+		case dir == "subscriptions" && name == "subscriptions.csv":
+			files.subscriptions = f
+		case dir == "playlists" && name == "playlists.csv":
+			files.playlists = f
+		case dir == "playlists" && path.Ext(name) == ".csv":
+			files.playlistFiles = append(files.playlistFiles, f)
+		case dir == "history" && name == "watch-history.json":
+			files.watchHistory = f
+		case dir == "history" && name == "search-history.json":
+			files.searchHistory = f
+		case dir == "history" && path.Ext(name) == ".html":
+			files.htmlHistory = true
+		}
+	}
+
+	return files
+}
+
+// importTakeoutSubscriptions adds the subscribed channels. Only the ID and title are known until the channel is
+// imported from the YouTube Data API.
+func importTakeoutSubscriptions(ctx context.Context, tx *sql.Tx, f *zip.File) (int, error) {
+	records, err := readZipCSV(f)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, r := range skipHeader(records) {
+		if len(r) < 3 || r[0] == "" {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", r[0], r[2])
+		if err != nil {
+			return count, fmt.Errorf("could not save channel: %s : %w", r[0], err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// importTakeoutPlaylists imports the playlists. Older archives have one CSV per playlist, with the playlist details at
+// the top. Newer archives list the playlists in playlists.csv and have a "<title>-videos.csv" file for each one.
+func importTakeoutPlaylists(ctx context.Context, tx *sql.Tx, progress Progress, index *zip.File, files []*zip.File) (int, error) {
+	playlistIDs := make(map[string]string) // title -> playlist ID, for newer archives
+	if index != nil {
+		records, err := readZipCSV(index)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, r := range skipHeader(records) {
+			// Playlist ID, Add new videos to top, Playlist title (original), Playlist title (original) language,
+			// Playlist create timestamp, Playlist update timestamp, Playlist video order, Playlist visibility
+			if len(r) < 8 {
+				continue
+			}
+
+			err := saveTakeoutPlaylist(ctx, tx, r[0], "", r[2], "", r[7], r[5])
+			if err != nil {
+				return 0, err
+			}
+			playlistIDs[r[2]] = r[0]
+		}
+	}
+
+	count := 0
+	for _, f := range files {
+		records, err := readZipCSV(f)
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		var playlistID string
+		var videos [][]string
+		switch {
+		case len(records) > 1 && strings.EqualFold(records[0][0], "Playlist Id") && len(records[1]) >= 7:
+			// Playlist Id, Channel Id, Time Created, Time Updated, Title, Description, Visibility
+			p := records[1]
+			playlistID = p[0]
+			err = saveTakeoutPlaylist(ctx, tx, p[0], p[1], p[4], p[5], p[6], p[3])
+			if err != nil {
+				return count, err
+			}
+			videos = records[2:]
+		default:
+			title := strings.TrimSuffix(path.Base(f.Name), "-videos.csv")
+			playlistID = playlistIDs[title]
+			videos = records
+		}
+
+		if playlistID == "" {
+			progress.Error(fmt.Errorf("unable to find playlist for %s", f.Name))
+			continue
+		}
+
+		err = saveTakeoutPlaylistVideos(ctx, tx, playlistID, skipHeader(videos))
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func saveTakeoutPlaylist(ctx context.Context, tx *sql.Tx, youtubeID, channelYouTubeID, title, description, visibility, updatedAt string) error {
+	var modifiedAt *int64
+	if t, ok := parseTakeoutTime(updatedAt); ok {
+		ts := t.Unix()
+		modifiedAt = &ts
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO playlists(youtube_id, channel_id, title, description, webpage_url, availability, modified_at)
+		VALUES(?, (SELECT id FROM channels WHERE youtube_id = ?), ?, ?, ?, ?, ?)
+		ON CONFLICT(youtube_id) DO UPDATE SET
+			channel_id = COALESCE(excluded.channel_id, channel_id),
+			title = excluded.title,
+			description = COALESCE(NULLIF(excluded.description, ''), description),
+			availability = excluded.availability,
+			modified_at = COALESCE(excluded.modified_at, modified_at)`,
+		youtubeID, channelYouTubeID, title, description, "https://www.youtube.com/playlist?list="+youtubeID, strings.ToLower(visibility), modifiedAt)
+	if err != nil {
+		return fmt.Errorf("%s: failed to save playlist: %w", youtubeID, err)
+	}
+
+	return nil
+}
+
+// saveTakeoutPlaylistVideos replaces the videos in a playlist. Each record starts with the video ID.
+func saveTakeoutPlaylistVideos(ctx context.Context, tx *sql.Tx, youtubeID string, records [][]string) error {
+	var playlistID int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM playlists WHERE youtube_id = ?", youtubeID).Scan(&playlistID)
+	if err != nil {
+		return fmt.Errorf("%s: unable to get database ID: %w", youtubeID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM playlist_videos WHERE playlist_id = ?", playlistID)
+	if err != nil {
+		return fmt.Errorf("%s: unable to clear playlist videos: %w", youtubeID, err)
+	}
+
+	position := 0
+	for _, r := range records {
+		videoID := strings.TrimSpace(r[0])
+		if videoID == "" {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO playlist_videos(playlist_id, position, video_youtube_id) VALUES(?, ?, ?)",
+			playlistID, position, videoID)
+		if err != nil {
+			return fmt.Errorf("%s: unable to save playlist video: %s : %w", youtubeID, videoID, err)
+		}
+		position++
+	}
+
+	return nil
+}
+
+// importTakeoutWatchHistory saves the watch history. Videos from channels that have been imported are added to the
+// videos table too.
+func importTakeoutWatchHistory(ctx context.Context, tx *sql.Tx, f *zip.File) (int, error) {
+	entries, err := readZipJSON[[]takeoutHistoryEntry](f)
+	if err != nil {
+		return 0, err
+	}
+
+	saveHistory, err := tx.PrepareContext(ctx, `INSERT INTO watch_history(video_youtube_id, title, channel_youtube_id, channel_title, watched_at)
+		VALUES(?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer saveHistory.Close()
+
+	saveVideo, err := tx.PrepareContext(ctx, `INSERT INTO videos(youtube_id, title, channel_id, webpage_url)
+		SELECT ?, ?, id, ? FROM channels WHERE youtube_id = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer saveVideo.Close()
+
+	count := 0
+	for _, e := range entries {
+		if len(e.Details) > 0 {
+			// ads are in the watch history too
+			continue
+		}
+
+		videoID := youtubeVideoID(e.TitleURL)
+		if videoID == "" {
+			// removed and private videos do not have a URL
+			continue
+		}
+
+		watchedAt, err := time.Parse(time.RFC3339, e.Time)
+		if err != nil {
+			return count, fmt.Errorf("%s: invalid time: %s : %w", videoID, e.Time, err)
+		}
+
+		// the prefix is translated into the account's language, so it is only removed for English archives
+		title := strings.TrimPrefix(e.Title, "Watched ")
+
+		var channelID, channelTitle string
+		if len(e.Subtitles) > 0 {
+			channelID = ChannelIDFromURL(e.Subtitles[0].URL)
+			channelTitle = e.Subtitles[0].Name
+		}
+
+		_, err = saveHistory.ExecContext(ctx, videoID, title, channelID, channelTitle, watchedAt.Unix())
+		if err != nil {
+			return count, fmt.Errorf("%s: unable to save watch history: %w", videoID, err)
+		}
+
+		if channelID != "" {
+			_, err = saveVideo.ExecContext(ctx, videoID, title, "https://www.youtube.com/watch?v="+videoID, channelID)
+			if err != nil {
+				return count, fmt.Errorf("%s: failed to save video: %w", videoID, err)
+			}
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func importTakeoutSearchHistory(ctx context.Context, tx *sql.Tx, f *zip.File) (int, error) {
+	entries, err := readZipJSON[[]takeoutHistoryEntry](f)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, e := range entries {
+		if len(e.Details) > 0 {
+			continue
+		}
+
+		u, err := url.Parse(e.TitleURL)
+		if err != nil {
+			continue
+		}
+
+		query := u.Query().Get("search_query")
+		if query == "" {
+			continue
+		}
+
+		searchedAt, err := time.Parse(time.RFC3339, e.Time)
+		if err != nil {
+			return count, fmt.Errorf("invalid time: %s : %w", e.Time, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO search_history(query, searched_at) VALUES(?, ?)", query, searchedAt.Unix())
+		if err != nil {
+			return count, fmt.Errorf("unable to save search history: %w", err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// youtubeVideoID gets the video ID from a watch URL.
+func youtubeVideoID(videoURL string) string {
+	u, err := url.Parse(videoURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Query().Get("v")
+}
+
+func parseTakeoutTime(s string) (time.Time, bool) {
+	for _, layout := range takeoutTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// skipHeader removes the header row and any blank rows from CSV records.
+func skipHeader(records [][]string) [][]string {
+	rows := make([][]string, 0, len(records))
+	for i, r := range records {
+		if i == 0 || len(r) == 0 || (len(r) == 1 && r[0] == "") {
+			continue
+		}
+
+		rows = append(rows, r)
+	}
+
+	return rows
+}
+
+func readZipCSV(f *zip.File) ([][]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	r := csv.NewReader(rc)
+	r.FieldsPerRecord = -1 // older playlist files have a different header at the top
+	r.LazyQuotes = true
+
+	return r.ReadAll()
+}
+
+func readZipJSON[T any](f *zip.File) (T, error) {
+	var v T
+
+	rc, err := f.Open()
+	if err != nil {
+		return v, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return v, err
+	}
+
+	err = json.Unmarshal(data, &v)
+	if err != nil {
+		return v, fmt.Errorf("%s is not valid JSON: %w", f.Name, err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestZip creates a zip file with the given files in a temporary directory.
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "takeout.zip")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = w.Write([]byte(contents))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// TestTakeoutImport imports an archive with both the older and newer playlist layouts, and history with ads and
+// removed videos in it. The top level folder is translated, so it is not named "YouTube and YouTube Music".
+func TestTakeoutImport(t *testing.T) {
+	ctx := WithProgress(context.Background(), &testProgress{})
+	db := openTestDB(t)
+
+	file := writeTestZip(t, map[string]string{
+		"Takeout/YouTube und YouTube Music/subscriptions/subscriptions.csv": "Channel Id,Channel Url,Channel Title\n" +
+			"UC0000000000000000000001,http://www.youtube.com/channel/UC0000000000000000000001,First\n" +
+			"UC0000000000000000000002,http://www.youtube.com/channel/UC0000000000000000000002,Second\n\n",
+		"Takeout/YouTube und YouTube Music/playlists/playlists.csv": "Playlist ID,Add new videos to top,Playlist title (original),Playlist title (original) language,Playlist create timestamp,Playlist update timestamp,Playlist video order,Playlist visibility\n" +
+			"PLnew,False,Favorites,,2024-01-01T00:00:00+00:00,2024-01-02T00:00:00+00:00,Manual,Public\n",
+		"Takeout/YouTube und YouTube Music/playlists/Favorites-videos.csv": "Video ID,Playlist video creation timestamp\n" +
+			"video000001,2024-01-01T00:00:00+00:00\n" +
+			"video000002,2024-01-01T00:00:00+00:00\n",
+		"Takeout/YouTube und YouTube Music/playlists/Old.csv": "Playlist Id,Channel Id,Time Created,Time Updated,Title,Description,Visibility\n" +
+			"PLold,UC0000000000000000000001,2020-01-01 00:00:00 UTC,2020-01-02 00:00:00 UTC,Old,Older layout,Private\n" +
+			"\n" +
+			"Video Id,Time Added\n" +
+			"video000003,2020-01-01 00:00:00 UTC\n",
+		"Takeout/YouTube und YouTube Music/history/watch-history.json": `[
+			{"title": "Watched First video", "titleUrl": "https://www.youtube.com/watch?v=video000001", "time": "2024-01-03T00:00:00Z",
+			 "subtitles": [{"name": "First", "url": "https://www.youtube.com/channel/UC0000000000000000000001"}]},
+			{"title": "Watched Unknown channel", "titleUrl": "https://www.youtube.com/watch?v=video000004", "time": "2024-01-04T00:00:00Z",
+			 "subtitles": [{"name": "Unknown", "url": "https://www.youtube.com/channel/UC0000000000000000000009"}]},
+			{"title": "Watched a video that has been removed", "time": "2024-01-05T00:00:00Z"},
+			{"title": "Watched Ad", "titleUrl": "https://www.youtube.com/watch?v=advideo0001", "time": "2024-01-06T00:00:00Z",
+			 "details": [{"name": "From Google Ads"}]}
+		]`,
+		"Takeout/YouTube und YouTube Music/history/search-history.json": `[
+			{"title": "Searched for cats", "titleUrl": "https://www.youtube.com/results?search_query=cats", "time": "2024-01-03T00:00:00Z"}
+		]`,
+	})
+
+	err := NewTakeoutImporter(db).Import(ctx, file)
+	if err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+
+	counts := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT COUNT(*) FROM channels", 2},
+		{"SELECT COUNT(*) FROM playlists", 2},
+		{"SELECT COUNT(*) FROM playlist_videos WHERE playlist_id = (SELECT id FROM playlists WHERE youtube_id = 'PLnew')", 2},
+		{"SELECT COUNT(*) FROM playlist_videos WHERE playlist_id = (SELECT id FROM playlists WHERE youtube_id = 'PLold')", 1},
+		{"SELECT COUNT(*) FROM playlists WHERE youtube_id = 'PLold' AND channel_id IS NOT NULL AND availability = 'private'", 1},
+		{"SELECT COUNT(*) FROM watch_history", 2},
+		{"SELECT COUNT(*) FROM watch_history WHERE title = 'First video'", 1},
+		// only videos from imported channels are added
+		{"SELECT COUNT(*) FROM videos", 1},
+		{"SELECT COUNT(*) FROM search_history WHERE query = 'cats'", 1},
+	}
+
+	for _, c := range counts {
+		var got int
+		err := db.QueryRow(c.query).Scan(&got)
+		if err != nil {
+			t.Fatalf("%s: %s", c.query, err)
+		}
+		if got != c.want {
+			t.Errorf("%s = %d, want %d", c.query, got, c.want)
+		}
+	}
+}
+
+// TestTakeoutTimes checks the timestamp formats of the different versions of the Takeout CSV files.
+func TestTakeoutTimes(t *testing.T) {
+	tests := map[string]int64{
+		"2024-01-02T00:00:00+00:00": 1704153600,
+		"2020-01-02 00:00:00 UTC":   1577923200,
+	}
+
+	for s, want := range tests {
+		got, ok := parseTakeoutTime(s)
+		if !ok || got.Unix() != want {
+			t.Errorf("parseTakeoutTime(%q) = %d, %t, want %d", s, got.Unix(), ok, want)
+		}
+	}
+
+	if _, ok := parseTakeoutTime("yesterday"); ok {
+		t.Error("parseTakeoutTime accepted an invalid time")
+	}
+}
@@ -0,0 +1,228 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultMediaDir is where downloaded images are stored.
+const DefaultMediaDir = "./media"
+
+// mirroredTables are the tables with image URLs that should be downloaded. Each table needs id, url, local_path,
+// download_status, and download_attempts columns.
+var mirroredTables = []string{"channel_thumbnails", "channel_banners", "video_thumbnails"}
+
+// imageExtensions is used when the URL does not have a file extension, which is the case for most channel images.
+var imageExtensions = map[string]string{
+	"image/gif":  ".gif",
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// goneStatuses are the responses for images that will never be downloaded, like the thumbnails of deleted videos.
+var goneStatuses = []int{http.StatusNotFound, http.StatusGone}
+
+// downloadError is an unexpected response to an image request.
+type downloadError struct {
+	status string
+	code   int
+}
+
+func (e *downloadError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.status)
+}
+
+// ThumbnailMirror downloads thumbnails and banners, so they can be served without going to the YouTube CDN. Images
+// are stored by the SHA-256 of their contents, so the same image is only stored once and a file never changes once it
+// has been written.
+type ThumbnailMirror struct {
+	db     *sql.DB
+	dir    string
+	client *http.Client
+}
+
+// NewThumbnailMirror creates a mirror that stores images in dir.
+func NewThumbnailMirror(db *sql.DB, dir string) *ThumbnailMirror {
+	return &ThumbnailMirror{
+		db:     db,
+		dir:    dir,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Mirror downloads every image that has not been downloaded yet. Images that can not be downloaded are skipped and
+// will be tried again the next time, unless their URL is gone. The failure is saved on the image's row either way.
+func (m *ThumbnailMirror) Mirror(ctx context.Context) error {
+	err := os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create media directory: dir = \"%s\": %w", m.dir, err)
+	}
+
+	progress := progressFrom(ctx)
+
+	var mErrs []error
+	for _, table := range mirroredTables {
+		images, err := m.pending(ctx, table)
+		if err != nil {
+			return fmt.Errorf("could not list images: table = \"%s\": %w", table, err)
+		}
+
+		progress.Start(fmt.Sprintf("downloading %s", table), int64(len(images)))
+		gone := 0
+		for id, imageURL := range images {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			progress.Add(1)
+
+			localPath, err := m.download(ctx, imageURL)
+			if err != nil {
+				status := 0
+				var dErr *downloadError
+				if errors.As(err, &dErr) {
+					status = dErr.code
+				}
+
+				_, sErr := m.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET download_status = NULLIF(?, 0), download_attempts = download_attempts + 1 WHERE id = ?", table), status, id)
+				if sErr != nil {
+					mErrs = append(mErrs, fmt.Errorf("could not save download status: table = \"%s\": id = %d: %w", table, id, sErr))
+				}
+
+				// gone images are expected, deleted videos keep their thumbnail URLs
+				if slices.Contains(goneStatuses, status) {
+					gone++
+					continue
+				}
+
+				mErrs = append(mErrs, fmt.Errorf("could not download image: url = \"%s\": %w", imageURL, err))
+				continue
+			}
+
+			_, err = m.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET local_path = ?, download_status = NULL WHERE id = ?", table), localPath, id)
+			if err != nil {
+				mErrs = append(mErrs, fmt.Errorf("could not save image path: table = \"%s\": id = %d: %w", table, id, err))
+			}
+		}
+
+		progress.Finish()
+
+		if gone > 0 {
+			progress.Logf("%s: %d images are gone and will not be downloaded again", table, gone)
+		}
+	}
+
+	return errors.Join(mErrs...)
+}
+
+// pending lists the images in table that still need to be downloaded, keyed by row ID. Images that are gone are
+// skipped. The rows are read up front so the query is not still open while the table is updated.
+func (m *ThumbnailMirror) pending(ctx context.Context, table string) (map[int64]string, error) {
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf("SELECT id, url FROM %s WHERE local_path IS NULL AND COALESCE(download_status, 0) NOT IN (?, ?)", table),
+		goneStatuses[0], goneStatuses[1])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var imageURL string
+		if err := rows.Scan(&id, &imageURL); err != nil {
+			return nil, err
+		}
+
+		images[id] = imageURL
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// download saves the image and returns its path relative to the media directory.
+func (m *ThumbnailMirror) download(ctx context.Context, imageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &downloadError{status: resp.Status, code: resp.StatusCode}
+	}
+
+	tmp, err := os.CreateTemp(m.dir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the file has been renamed
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	closeErr := tmp.Close()
+	if err != nil || closeErr != nil {
+		return "", errors.Join(err, closeErr)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	localPath := path.Join(sum[:2], sum+imageExtension(imageURL, resp.Header.Get("Content-Type")))
+	file := filepath.Join(m.dir, filepath.FromSlash(localPath))
+
+	if _, err := os.Stat(file); err == nil {
+		// already have this image
+		return localPath, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmp.Name(), file)
+	if err != nil {
+		return "", err
+	}
+
+	return localPath, nil
+}
+
+func imageExtension(imageURL string, contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if ext, ok := imageExtensions[strings.TrimSpace(mediaType)]; ok {
+		return ext
+	}
+
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return ""
+	}
+
+	return path.Ext(u.Path)
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// saveVideoTopics saves the video's topics and links them to the video. Topics are the Wikipedia URLs from the
+// YouTube Data API's topicDetails.topicCategories.
+func saveVideoTopics(ctx context.Context, db *sql.DB, videoID int64, topicURLs []string) error {
+	var tErrs []error
+
+	for _, topicURL := range topicURLs {
+		_, err := db.ExecContext(ctx, "INSERT INTO video_topics(name, url) VALUES(?, ?)", videoTopicName(topicURL), topicURL)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save topic: %s : %w", topicURL, err))
+			continue
+		}
+
+		_, err = db.ExecContext(ctx, "INSERT INTO videos_video_topics(video_id, topic_id) SELECT ?, id FROM video_topics WHERE url = ?", videoID, topicURL)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not assign topic to video: %s : %w", topicURL, err))
+		}
+	}
+
+	return errors.Join(tErrs...)
+}
+
+// videoTopicName gets a readable name from a topic's Wikipedia URL.
+// ex. https://en.wikipedia.org/wiki/Role-playing_video_game = Role-playing video game
+func videoTopicName(topicURL string) string {
+	u, err := url.Parse(topicURL)
+	if err != nil {
+		return topicURL
+	}
+
+	name, err := url.PathUnescape(path.Base(u.EscapedPath()))
+	if err != nil {
+		name = path.Base(u.Path)
+	}
+
+	return strings.ReplaceAll(name, "_", " ")
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+)
+
+// ImportUploads imports the videos uploaded by the given channels. If no channels are given, uploads for every
+// channel in the database are imported. Channels are queued like ImportChannels, so an import that runs out of quota
+// can be resumed by running it again.
+func (yi *YouTubeImporter) ImportUploads(ctx context.Context, channelIDs []string) error {
+	progress := progressFrom(ctx)
+	queue := NewQueue(yi.db, "uploads")
+
+	pending, err := queue.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load import queue: %w", err)
+	}
+
+	switch {
+	case len(pending) > 0:
+		progress.Logf("Resuming previous import: %d channels remaining", len(pending))
+		channelIDs = pending
+	case len(channelIDs) == 0:
+		channelIDs, err = getChannelYouTubeIDs(ctx, yi.db)
+		if err != nil {
+			return fmt.Errorf("unable to list channels: %w", err)
+		}
+	}
+
+	err = queue.Push(ctx, channelIDs...)
+	if err != nil {
+		return fmt.Errorf("unable to queue channels: %w", err)
+	}
+
+	progress.Start("importing uploads", int64(len(channelIDs)))
+	for i, channelID := range channelIDs {
+		progress.Logf("- %d, importing uploads (Channel ID: %s)", i+1, channelID)
+
+		err := yi.importChannelUploads(ctx, channelID)
+		if IsQuotaError(err) {
+			progress.Finish()
+			return fmt.Errorf("%d channels were not imported: %w", len(channelIDs)-i, err)
+		}
+		if err != nil {
+			progress.Error(fmt.Errorf("unable to import uploads: %w", err))
+		}
+
+		err = queue.Done(ctx, channelID)
+		if err != nil {
+			progress.Error(fmt.Errorf("unable to update import queue: %w", err))
+		}
+		progress.Add(1)
+	}
+	progress.Finish()
+
+	quota := yi.youtube.Quota()
+	progress.Logf("Quota used today: %d units, %d remaining", quota.Used(), quota.Remaining())
+
+	return nil
+}
+
+func (yi *YouTubeImporter) importChannelUploads(ctx context.Context, youtubeID string) error {
+	var channelID int64
+	var uploadsPlaylistID sql.NullString
+
+	err := yi.db.QueryRowContext(ctx, "SELECT id, uploads_playlist_id FROM channels WHERE youtube_id = ?", youtubeID).
+		Scan(&channelID, &uploadsPlaylistID)
+	if err != nil {
+		return fmt.Errorf("missing channel: channel ID = \"%s\": %w", youtubeID, err)
+	}
+
+	if !uploadsPlaylistID.Valid || uploadsPlaylistID.String == "" {
+		return fmt.Errorf("channel has no uploads playlist: channel ID = \"%s\"", youtubeID)
+	}
+
+	items, err := yi.youtube.PlaylistItems(ctx, uploadsPlaylistID.String)
+	if err != nil {
+		return err
+	}
+
+	videoIDs := make([]string, 0, len(items))
+	listed := make(map[string]bool, len(items))
+	var listedUntil int64
+	for _, item := range items {
+		videoIDs = append(videoIDs, item.ContentDetails.VideoId)
+		listed[item.ContentDetails.VideoId] = true
+
+		t, err := time.Parse(time.RFC3339, item.ContentDetails.VideoPublishedAt)
+		if err == nil && t.Unix() > listedUntil {
+			listedUntil = t.Unix()
+		}
+	}
+
+	var vErrs []error
+	available := make(map[string]bool, len(videoIDs))
+	for page := range slices.Chunk(videoIDs, MaxIDsPerRequest) {
+		videos, err := yi.youtube.Videos(ctx, page)
+		if err != nil {
+			return errors.Join(append(vErrs, err)...)
+		}
+
+		categoryIDs := make([]string, 0, len(videos))
+		for _, v := range videos {
+			if v.Snippet != nil && v.Snippet.CategoryId != "" && !slices.Contains(categoryIDs, v.Snippet.CategoryId) {
+				categoryIDs = append(categoryIDs, v.Snippet.CategoryId)
+			}
+		}
+
+		err = yi.saveVideoCategories(ctx, categoryIDs)
+		if IsQuotaError(err) {
+			return errors.Join(append(vErrs, err)...)
+		}
+		if err != nil {
+			// videos are still linked to a placeholder category
+			vErrs = append(vErrs, fmt.Errorf("unable to save video categories: %w", err))
+		}
+
+		for _, v := range videos {
+			available[v.Id] = true
+
+			err := saveAPIVideo(ctx, yi.db, channelID, v)
+			if err != nil {
+				vErrs = append(vErrs, fmt.Errorf("%s: failed to save video: %w", v.Id, err))
+			}
+		}
+	}
+
+	unavailable, err := markUnavailableVideos(ctx, yi.db, channelID, listed, available, listedUntil)
+	if err != nil {
+		vErrs = append(vErrs, fmt.Errorf("unable to mark unavailable videos: %w", err))
+	}
+
+	if len(unavailable) > 0 {
+		events.Publish(ctx, events.Event{Type: events.VideosUnavailable, ChannelIDs: []int64{channelID}, VideoIDs: unavailable})
+	}
+
+	return errors.Join(vErrs...)
+}
+
+// markUnavailableVideos records when the channel's videos stopped being returned by the API. Private and deleted videos
+// are either missing from the uploads playlist or not returned when they are looked up, so the API can not tell them
+// apart. Videos published after the newest video in the playlist are skipped, since the playlist may be an older cached
+// response. Videos that come back are marked as available again. The row IDs of the videos that just became unavailable
+// are returned.
+func markUnavailableVideos(ctx context.Context, db *sql.DB, channelID int64, listed map[string]bool, available map[string]bool, listedUntil int64) ([]int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, youtube_id, COALESCE(published_at, 0), unavailable_at IS NOT NULL FROM videos WHERE channel_id = ?", channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unavailable, returned []int64
+	for rows.Next() {
+		var id int64
+		var youtubeID string
+		var publishedAt int64
+		var wasUnavailable bool
+		if err := rows.Scan(&id, &youtubeID, &publishedAt, &wasUnavailable); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case available[youtubeID] && wasUnavailable:
+			returned = append(returned, id)
+		case available[youtubeID] || wasUnavailable:
+		case listed[youtubeID] || (publishedAt != 0 && publishedAt <= listedUntil):
+			unavailable = append(unavailable, id)
+		}
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	for _, id := range unavailable {
+		_, err = tx.ExecContext(ctx, "UPDATE videos SET unavailable_at = ? WHERE id = ?", now, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range returned {
+		_, err = tx.ExecContext(ctx, "UPDATE videos SET unavailable_at = NULL WHERE id = ?", id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return unavailable, tx.Commit()
+}
@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sosodev/duration"
+	"google.golang.org/api/youtube/v3"
+)
+
+// saveAPIVideo saves a video returned by the YouTube Data API. Videos that were already added by another importer are
+// updated, so they get the title, statistics, and duration from the API.
+func saveAPIVideo(ctx context.Context, db *sql.DB, channelID int64, v *youtube.Video) error {
+	var publishedAt *int64
+	if v.Snippet != nil && v.Snippet.PublishedAt != "" {
+		t, err := time.Parse(time.RFC3339, v.Snippet.PublishedAt)
+		if err == nil {
+			ts := t.Unix()
+			publishedAt = &ts
+		}
+	}
+
+	var seconds *int64
+	if v.ContentDetails != nil && v.ContentDetails.Duration != "" {
+		d, err := duration.Parse(v.ContentDetails.Duration)
+		if err == nil {
+			s := int64(d.ToTimeDuration().Seconds())
+			seconds = &s
+		}
+	}
+
+	snippet := v.Snippet
+	if snippet == nil {
+		snippet = &youtube.VideoSnippet{}
+	}
+
+	contentDetails := v.ContentDetails
+	if contentDetails == nil {
+		contentDetails = &youtube.VideoContentDetails{}
+	}
+
+	statistics := v.Statistics
+	if statistics == nil {
+		statistics = &youtube.VideoStatistics{}
+	}
+
+	status := v.Status
+	if status == nil {
+		status = &youtube.VideoStatus{}
+	}
+
+	_, err := db.ExecContext(ctx, `INSERT INTO videos(
+		youtube_id,
+		published_at,
+		channel_id,
+		title,
+		description,
+		category_id,
+		duration,
+		definition,
+		is_licensed_content,
+		privacy_status,
+		webpage_url,
+		view_count,
+		like_count,
+		favorite_count,
+		comment_count
+) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT(youtube_id) DO UPDATE SET
+		published_at = COALESCE(excluded.published_at, published_at),
+		channel_id = excluded.channel_id,
+		title = excluded.title,
+		description = excluded.description,
+		category_id = excluded.category_id,
+		duration = COALESCE(excluded.duration, duration),
+		definition = excluded.definition,
+		is_licensed_content = excluded.is_licensed_content,
+		privacy_status = excluded.privacy_status,
+		webpage_url = COALESCE(webpage_url, excluded.webpage_url),
+		view_count = excluded.view_count,
+		like_count = excluded.like_count,
+		favorite_count = excluded.favorite_count,
+		comment_count = excluded.comment_count`,
+		v.Id,
+		publishedAt,
+		channelID,
+		snippet.Title,
+		snippet.Description,
+		snippet.CategoryId,
+		seconds,
+		contentDetails.Definition,
+		contentDetails.LicensedContent,
+		status.PrivacyStatus,
+		"https://www.youtube.com/watch?v="+v.Id,
+		statistics.ViewCount,
+		statistics.LikeCount,
+		statistics.FavoriteCount,
+		statistics.CommentCount)
+	if err != nil {
+		return err
+	}
+
+	var videoID int64
+	err = db.QueryRowContext(ctx, "SELECT id FROM videos WHERE youtube_id = ?", v.Id).Scan(&videoID)
+	if err != nil {
+		return fmt.Errorf("unable to get database ID: %w", err)
+	}
+
+	err = saveVideoThumbnails(ctx, db, videoID, snippet.Thumbnails)
+	if err != nil {
+		return fmt.Errorf("unable to save thumbnails: %w", err)
+	}
+
+	if snippet.CategoryId != "" {
+		categoryID, err := VideoCategoryByYouTubeID(ctx, db, snippet.CategoryId, "")
+		if err != nil {
+			return fmt.Errorf("unable to get category ID: %w", err)
+		}
+
+		err = LinkVideoCategory(ctx, db, videoID, categoryID)
+		if err != nil {
+			return fmt.Errorf("unable to assign category to video: %w", err)
+		}
+	}
+
+	if v.TopicDetails != nil {
+		// videos imported from yt-dlp are matched by YouTube ID, so they get topics too
+		err = saveVideoTopics(ctx, db, videoID, v.TopicDetails.TopicCategories)
+		if err != nil {
+			return fmt.Errorf("unable to save topics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func saveVideoThumbnails(ctx context.Context, db *sql.DB, videoID int64, thumbnails *youtube.ThumbnailDetails) error {
+	if thumbnails == nil {
+		return nil
+	}
+
+	sizes := map[string]*youtube.Thumbnail{
+		"default":  thumbnails.Default,
+		"high":     thumbnails.High,
+		"maxres":   thumbnails.Maxres,
+		"medium":   thumbnails.Medium,
+		"standard": thumbnails.Standard,
+	}
+
+	var tErrs []error
+	for size, t := range sizes {
+		if t == nil {
+			continue
+		}
+
+		_, err := db.ExecContext(ctx, `INSERT INTO video_thumbnails(video_id, size, width, height, url) VALUES(?, ?, ?, ?, ?)
+			ON CONFLICT(video_id, size) DO UPDATE SET width = excluded.width, height = excluded.height, url = excluded.url,
+				local_path = NULL, download_status = NULL, download_attempts = 0 WHERE url != excluded.url`,
+			videoID, size, t.Width, t.Height, t.Url)
+		if err != nil {
+			tErrs = append(tErrs, fmt.Errorf("could not save thumbnail: %s : %w", size, err))
+		}
+	}
+
+	return errors.Join(tErrs...)
+}
+
+func getChannelYouTubeIDs(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT youtube_id FROM channels ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
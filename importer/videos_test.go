@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// TestSaveAPIVideoUpdatesExisting checks that a video added by the feed poller or Takeout is filled in from the API,
+// instead of keeping its partial row.
+func TestSaveAPIVideoUpdatesExisting(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	channelID := mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+	videoID := mustExec(t, db, "INSERT INTO videos(youtube_id, title, channel_id, published_at) VALUES(?, ?, ?, ?)",
+		"apivideo001", "Old title", channelID, 1700000000)
+	mustExec(t, db, "INSERT INTO video_thumbnails(video_id, size, width, height, url, local_path) VALUES(?, ?, ?, ?, ?, ?)",
+		videoID, "high", 480, 360, "https://i.ytimg.com/vi/apivideo001/old.jpg", "video/apivideo001/high.jpg")
+
+	v := &youtube.Video{
+		Id: "apivideo001",
+		Snippet: &youtube.VideoSnippet{
+			Title: "New title",
+			Thumbnails: &youtube.ThumbnailDetails{
+				High: &youtube.Thumbnail{Width: 480, Height: 360, Url: "https://i.ytimg.com/vi/apivideo001/hqdefault.jpg"},
+			},
+		},
+		ContentDetails: &youtube.VideoContentDetails{Duration: "PT1M1S"},
+		Statistics:     &youtube.VideoStatistics{ViewCount: 42},
+	}
+
+	for range 2 {
+		err := saveAPIVideo(ctx, db, channelID, v)
+		if err != nil {
+			t.Fatalf("saveAPIVideo: %s", err)
+		}
+	}
+
+	var title string
+	var duration, views, publishedAt, count int64
+	err := db.QueryRow("SELECT title, duration, view_count, published_at, (SELECT COUNT(*) FROM videos) FROM videos WHERE id = ?", videoID).
+		Scan(&title, &duration, &views, &publishedAt, &count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "New title" || duration != 61 || views != 42 || publishedAt != 1700000000 || count != 1 {
+		t.Errorf("video was not updated: title = %q, duration = %d, views = %d, published_at = %d, videos = %d", title, duration, views, publishedAt, count)
+	}
+
+	var url string
+	var localPath *string
+	err = db.QueryRow("SELECT url, local_path FROM video_thumbnails WHERE video_id = ? AND size = 'high'", videoID).Scan(&url, &localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != v.Snippet.Thumbnails.High.Url || localPath != nil {
+		t.Errorf("changed thumbnail was not replaced: url = %q, local_path = %v", url, localPath)
+	}
+}
@@ -0,0 +1,290 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// MaxIDsPerRequest is the most IDs the YouTube Data API will accept in a single list request.
+const MaxIDsPerRequest = 50
+
+// part lists for each kind of resource
+var (
+	channelParts      = []string{"snippet", "brandingSettings", "id", "statistics", "topicDetails", "contentDetails"}
+	subscriptionParts = []string{"snippet", "contentDetails", "id"}
+	playlistItemParts = []string{"snippet", "contentDetails", "id"}
+	videoParts        = []string{"snippet", "contentDetails", "id", "statistics", "status", "topicDetails"}
+)
+
+// YouTube wraps the YouTube Data API so responses are cached and every call is counted against the quota. Anything
+// found in the cache is not requested again until it expires, which lets an import that stopped early pick up where it
+// left off.
+type YouTube struct {
+	service *youtube.Service
+	cache   Cache
+	quota   *Quota
+}
+
+// NewYouTube creates a cached YouTube Data API client.
+func NewYouTube(service *youtube.Service, cache Cache, quota *Quota) *YouTube {
+	return &YouTube{
+		service: service,
+		cache:   cache,
+		quota:   quota,
+	}
+}
+
+// Quota is the quota ledger API calls are counted against.
+func (yt *YouTube) Quota() *Quota {
+	return yt.quota
+}
+
+// Channels gets the channels with the given IDs. At most MaxIDsPerRequest IDs can be requested at a time. Channels
+// that no longer exist are not returned.
+func (yt *YouTube) Channels(ctx context.Context, ids []string) ([]*youtube.Channel, error) {
+	channels, uncached := cachedItems[youtube.Channel](yt.cache, "channel", ids)
+	if len(uncached) == 0 {
+		return channels, nil
+	}
+
+	err := yt.quota.Spend(ctx, "channels.list")
+	if err != nil {
+		return channels, err
+	}
+
+	response, err := yt.service.Channels.List(channelParts).
+		Id(uncached...).
+		MaxResults(MaxIDsPerRequest).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return channels, fmt.Errorf("error listing channels: %w", err)
+	}
+
+	for _, c := range response.Items {
+		yt.put(cacheKey("channel", c.Id), c)
+	}
+
+	return append(channels, response.Items...), nil
+}
+
+// ChannelIDForHandle finds the ID of the channel with the given handle, with or without the @. An empty ID is returned
+// if no channel has the handle. Lookups are cached, including handles that were not found.
+func (yt *YouTube) ChannelIDForHandle(ctx context.Context, handle string) (string, error) {
+	return yt.channelIDFor(ctx, "handle", strings.TrimPrefix(handle, "@"))
+}
+
+// ChannelIDForUsername finds the ID of the channel with the given legacy username, the name in /user/ URLs. An empty ID
+// is returned if no channel has the username. Lookups are cached, including usernames that were not found.
+func (yt *YouTube) ChannelIDForUsername(ctx context.Context, username string) (string, error) {
+	return yt.channelIDFor(ctx, "username", username)
+}
+
+func (yt *YouTube) channelIDFor(ctx context.Context, kind string, name string) (string, error) {
+	// handles and usernames are not case-sensitive
+	key := cacheKey(kind, strings.ToLower(name))
+
+	var id string
+	if yt.cache.Has(key) && yt.cache.Get(key, &id) == nil {
+		return id, nil
+	}
+
+	err := yt.quota.Spend(ctx, "channels.list")
+	if err != nil {
+		return "", err
+	}
+
+	call := yt.service.Channels.List(channelParts)
+	if kind == "handle" {
+		call.ForHandle(name)
+	} else {
+		call.ForUsername(name)
+	}
+
+	response, err := call.Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("error listing channels: %s = \"%s\": %w", kind, name, err)
+	}
+
+	if len(response.Items) > 0 {
+		c := response.Items[0]
+		id = c.Id
+
+		// the whole channel came back, so importing it does not need another request
+		yt.put(cacheKey("channel", c.Id), c)
+	}
+	yt.put(key, id)
+
+	return id, nil
+}
+
+// Subscriptions gets all the authorized user's subscriptions. The list is cached like every other response, so new
+// subscriptions are found once the cached list expires, or right away with a refresh cache.
+func (yt *YouTube) Subscriptions(ctx context.Context) ([]*youtube.Subscription, error) {
+	key := cacheKey("subscriptions", "mine")
+
+	var subscriptions []*youtube.Subscription
+	if yt.cache.Has(key) && yt.cache.Get(key, &subscriptions) == nil {
+		return subscriptions, nil
+	}
+
+	call := yt.service.Subscriptions.List(subscriptionParts).
+		Mine(true).
+		MaxResults(MaxIDsPerRequest) // largest page size allowed, fewer pages uses less quota
+
+	for {
+		err := yt.quota.Spend(ctx, "subscriptions.list")
+		if err != nil {
+			return subscriptions, err
+		}
+
+		page, err := call.Context(ctx).Do()
+		if err != nil {
+			return subscriptions, fmt.Errorf("error listing subscriptions: %w", err)
+		}
+
+		subscriptions = append(subscriptions, page.Items...)
+
+		if page.NextPageToken == "" {
+			break
+		}
+		call.PageToken(page.NextPageToken)
+	}
+
+	// only cache the complete list, a partial list would hide subscriptions on the next run
+	yt.put(key, subscriptions)
+
+	return subscriptions, nil
+}
+
+// PlaylistItems gets all the items in a playlist.
+func (yt *YouTube) PlaylistItems(ctx context.Context, playlistID string) ([]*youtube.PlaylistItem, error) {
+	key := cacheKey("playlistItems", playlistID)
+
+	var items []*youtube.PlaylistItem
+	if yt.cache.Has(key) && yt.cache.Get(key, &items) == nil {
+		return items, nil
+	}
+
+	call := yt.service.PlaylistItems.List(playlistItemParts).
+		PlaylistId(playlistID).
+		MaxResults(MaxIDsPerRequest)
+
+	for {
+		err := yt.quota.Spend(ctx, "playlistItems.list")
+		if err != nil {
+			return items, err
+		}
+
+		page, err := call.Context(ctx).Do()
+		if err != nil {
+			return items, fmt.Errorf("error listing playlist items: playlist = \"%s\": %w", playlistID, err)
+		}
+
+		items = append(items, page.Items...)
+
+		if page.NextPageToken == "" {
+			break
+		}
+		call.PageToken(page.NextPageToken)
+	}
+
+	yt.put(key, items)
+
+	return items, nil
+}
+
+// Videos gets the videos with the given IDs. At most MaxIDsPerRequest IDs can be requested at a time. Videos that
+// are private or deleted are not returned.
+func (yt *YouTube) Videos(ctx context.Context, ids []string) ([]*youtube.Video, error) {
+	videos, uncached := cachedItems[youtube.Video](yt.cache, "video", ids)
+	if len(uncached) == 0 {
+		return videos, nil
+	}
+
+	err := yt.quota.Spend(ctx, "videos.list")
+	if err != nil {
+		return videos, err
+	}
+
+	response, err := yt.service.Videos.List(videoParts).
+		Id(uncached...).
+		MaxResults(MaxIDsPerRequest).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return videos, fmt.Errorf("error listing videos: %w", err)
+	}
+
+	for _, v := range response.Items {
+		yt.put(cacheKey("video", v.Id), v)
+	}
+
+	return append(videos, response.Items...), nil
+}
+
+// VideoCategories gets the video categories with the given IDs.
+func (yt *YouTube) VideoCategories(ctx context.Context, ids []string) ([]*youtube.VideoCategory, error) {
+	categories, uncached := cachedItems[youtube.VideoCategory](yt.cache, "videoCategory", ids)
+	if len(uncached) == 0 {
+		return categories, nil
+	}
+
+	err := yt.quota.Spend(ctx, "videoCategories.list")
+	if err != nil {
+		return categories, err
+	}
+
+	response, err := yt.service.VideoCategories.List([]string{"snippet"}).
+		Id(uncached...).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return categories, fmt.Errorf("error listing video categories: %w", err)
+	}
+
+	for _, c := range response.Items {
+		yt.put(cacheKey("videoCategory", c.Id), c)
+	}
+
+	return append(categories, response.Items...), nil
+}
+
+func (yt *YouTube) put(key string, item any) {
+	err := yt.cache.Put(key, item)
+	if err != nil {
+		// a cache miss on the next run only costs quota, so there is no reason to stop the import
+		fmt.Printf("...unable to cache %s: %s\n", key, err)
+	}
+}
+
+// cachedItems splits ids into the items that are already cached and the IDs that still need to be requested.
+func cachedItems[T any](cache Cache, kind string, ids []string) ([]*T, []string) {
+	items := make([]*T, 0, len(ids))
+	uncached := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		key := cacheKey(kind, id)
+		if !cache.Has(key) {
+			uncached = append(uncached, id)
+			continue
+		}
+
+		item := new(T)
+		if err := cache.Get(key, item); err != nil {
+			uncached = append(uncached, id)
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, uncached
+}
+
+func cacheKey(kind string, id string) string {
+	return kind + CacheKeySeparator + id
+}
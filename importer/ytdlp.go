@@ -0,0 +1,803 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ytdlpBatchSize is how many videos are saved per transaction. Each video gets its own savepoint, so a video that
+// fails to save does not undo the rest of the batch.
+const ytdlpBatchSize = 100
+
+// YTDLPFormat is one of the formats listed in a yt-dlp info.json file.
+type YTDLPFormat struct {
+	// this mixes in image, audio, and video formats so we are not guaranteed to have all fields
+	ABR              *float64 `json:"abr"`
+	AudioCodec       *string  `json:"acodec"`
+	AspectRatio      *float64 `json:"aspect_ratio"`
+	ASR              *int64   `json:"asr"`
+	AudioChannels    *int64   `json:"audio_channels"`
+	AudioExt         *string  `json:"audio_ext"`
+	Columns          *int64   `json:"columns"`
+	Container        *string  `json:"container"`
+	Duration         *float64 `json:"duration"`
+	DynamicRange     *string  `json:"dynamic_range"`
+	Ext              *string  `json:"ext"`
+	FileSize         *int64   `json:"filesize"`
+	FileSizeApprox   *int64   `json:"filesize_approx"`
+	Format           *string  `json:"format"`
+	YouTubeFormatID  *string  `json:"format_id"`
+	FormatNote       *string  `json:"format_note"`
+	FPS              *float64 `json:"fps"`
+	HasDRM           *bool    `json:"has_drm"`
+	Height           *int64   `json:"height"`
+	Language         *string  `json:"language"`
+	Quality          *float64 `json:"quality"`
+	Resolution       *string  `json:"resolution"`
+	Rows             *int64   `json:"rows"`
+	SourcePreference *int64   `json:"source_preference"`
+	TBR              *float64 `json:"tbr"`
+	URL              *string  `json:"url"`
+	VBR              *float64 `json:"vbr"`
+	VideoCodec       *string  `json:"vcodec"`
+	VideoExt         *string  `json:"video_ext"`
+	Width            *int64   `json:"width"`
+	Requested        *int64   `json:"requested"`
+}
+
+// YTDLPVideo is the video metadata from a yt-dlp info.json file.
+type YTDLPVideo struct {
+	YouTubeID         string                     `json:"id"`
+	Title             string                     `json:"title"`
+	Type              string                     `json:"_type"`
+	Description       string                     `json:"description"`
+	ChannelID         string                     `json:"channel_id"`
+	Duration          int64                      `json:"duration"`
+	WebpageURL        string                     `json:"webpage_url"`
+	UploadedAt        int64                      `json:"timestamp"`
+	Availability      string                     `json:"availability"`
+	OriginalURL       string                     `json:"original_url"`
+	FullTitle         string                     `json:"fulltitle"`
+	Epoch             int64                      `json:"epoch"`
+	Format            string                     `json:"format"`
+	FormatID          string                     `json:"format_id"`
+	FormatNote        string                     `json:"format_note"`
+	Ext               string                     `json:"ext"`
+	FileSize          int64                      `json:"filesize_approx"`
+	TBR               float32                    `json:"tbr"`
+	Width             int64                      `json:"width"`
+	Height            int64                      `json:"height"`
+	Resolution        string                     `json:"resolution"`
+	DynamicRange      string                     `json:"dynamic_range"`
+	VideoCodec        string                     `json:"vcodec"`
+	VBR               float32                    `json:"vbr"`
+	AudioCodec        string                     `json:"acodec"`
+	AspectRatio       float32                    `json:"aspect_ratio"` // < 1 = shorts/vert?
+	ABR               float32                    `json:"abr"`
+	ASR               int64                      `json:"asr"`
+	Categories        []string                   `json:"categories"`
+	Tags              []string                   `json:"tags"`
+	Formats           []YTDLPFormat              `json:"formats"`
+	RequestedFormats  []YTDLPFormat              `json:"requested_formats"`
+	Chapters          []YTDLPChapter             `json:"chapters"`
+	Subtitles         map[string][]YTDLPSubtitle `json:"subtitles"`
+	AutomaticCaptions map[string][]YTDLPSubtitle `json:"automatic_captions"`
+	Comments          []YTDLPComment             `json:"comments"` // only written with --write-comments
+}
+
+// YTDLPChapter is a chapter of a video. Times are in seconds from the start of the video.
+type YTDLPChapter struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Title     string  `json:"title"`
+}
+
+// YTDLPComment is a comment on a video. Top level comments have "root" as their parent.
+type YTDLPComment struct {
+	ID               string `json:"id"`
+	Parent           string `json:"parent"`
+	Text             string `json:"text"`
+	Author           string `json:"author"`
+	AuthorID         string `json:"author_id"`
+	AuthorThumbnail  string `json:"author_thumbnail"`
+	AuthorIsUploader bool   `json:"author_is_uploader"`
+	AuthorIsVerified bool   `json:"author_is_verified"`
+	LikeCount        *int64 `json:"like_count"`
+	IsFavorited      bool   `json:"is_favorited"`
+	IsPinned         bool   `json:"is_pinned"`
+	Timestamp        *int64 `json:"timestamp"`
+}
+
+// YTDLPSubtitle is one of the formats a subtitle language is available in.
+type YTDLPSubtitle struct {
+	Ext  string `json:"ext"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+// YTDLPImporter imports video metadata from the info.json files written by yt-dlp.
+type YTDLPImporter struct {
+	db      *sql.DB
+	workers int
+}
+
+// NewYTDLPImporter creates an importer that parses info.json files using the given number of workers. If workers is
+// less than 1, one worker per CPU is used.
+func NewYTDLPImporter(db *sql.DB, workers int) *YTDLPImporter {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	return &YTDLPImporter{
+		db:      db,
+		workers: workers,
+	}
+}
+
+// ytdlpFile is the result of reading an info.json file. video and playlist are nil for other JSON files.
+type ytdlpFile struct {
+	path      string
+	hash      string
+	video     *YTDLPVideo
+	playlist  *YTDLPPlaylist
+	unchanged bool
+	err       error
+}
+
+// Import imports the JSON files written by yt-dlp. Each input can be a directory to scan recursively, a single JSON
+// file, a .zip, .tar, or .tar.gz archive, or StdinInput to read JSON from stdin. Files are parsed in parallel, but saved
+// one at a time since SQLite only allows a single writer. Files that have not changed since they were last imported are
+// skipped.
+func (yi *YTDLPImporter) Import(ctx context.Context, inputs ...string) error {
+	in, err := findYTDLPInputs(inputs)
+	if err != nil {
+		return err
+	}
+
+	imported, err := yi.importedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load imported files: %w", err)
+	}
+
+	stmts, err := prepareYTDLPStatements(ctx, yi.db)
+	if err != nil {
+		return fmt.Errorf("unable to prepare statements: %w", err)
+	}
+	defer stmts.Close()
+
+	sources := make(chan ytdlpSource)
+	go func() {
+		defer close(sources)
+		in.read(ctx, sources)
+	}()
+
+	parsed := make(chan ytdlpFile)
+	var wg sync.WaitGroup
+	for range yi.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for src := range sources {
+				parsed <- readYTDLPFile(src, imported[src.path])
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(parsed)
+	}()
+
+	progress := progressFrom(ctx)
+	progress.Start("importing videos", in.count())
+
+	var errs []error
+	// errors are reported as they happen, so they show up while the import is still running
+	reported := 0
+	report := func() {
+		for _, err := range errs[reported:] {
+			progress.Error(err)
+		}
+		reported = len(errs)
+	}
+
+	var saved []ytdlpFile
+	unchanged := 0
+	batch := make([]ytdlpFile, 0, ytdlpBatchSize)
+	for f := range parsed {
+		progress.Add(1)
+
+		switch {
+		case f.err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", f.path, f.err))
+			report()
+			continue
+		case f.unchanged:
+			unchanged++
+			continue
+		}
+
+		// files that are not videos or playlists are still saved, so they are not parsed again next time
+		batch = append(batch, f)
+		if len(batch) == ytdlpBatchSize {
+			saved = append(saved, yi.saveBatch(ctx, stmts, batch, &errs)...)
+			batch = batch[:0]
+			report()
+		}
+	}
+
+	if len(batch) > 0 {
+		saved = append(saved, yi.saveBatch(ctx, stmts, batch, &errs)...)
+		report()
+	}
+	progress.Finish()
+
+	videos := 0
+	playlists := 0
+	for _, f := range saved {
+		switch {
+		case f.video != nil:
+			videos++
+		case f.playlist != nil:
+			playlists++
+		}
+	}
+
+	progress.Logf("imported %d videos and %d playlists, %d files unchanged, skipped %d other files, %d failed",
+		videos, playlists, unchanged, len(saved)-videos-playlists, len(errs))
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d files could not be imported", len(errs))
+	}
+
+	return nil
+}
+
+// saveBatch saves the files in a single transaction and returns the files that were saved.
+func (yi *YTDLPImporter) saveBatch(ctx context.Context, stmts *ytdlpStatements, batch []ytdlpFile, errs *[]error) []ytdlpFile {
+	tx, err := yi.db.BeginTx(ctx, nil)
+	if err != nil {
+		for _, f := range batch {
+			*errs = append(*errs, fmt.Errorf("%s: unable to start transaction: %w", f.path, err))
+		}
+		return nil
+	}
+	defer tx.Rollback()
+
+	txStmts := stmts.Tx(ctx, tx)
+
+	saved := make([]ytdlpFile, 0, len(batch))
+	for _, f := range batch {
+		_, err := tx.ExecContext(ctx, "SAVEPOINT video")
+		if err == nil {
+			err = saveYTDLPFile(ctx, tx, txStmts, f)
+			if err != nil {
+				_, rbErr := tx.ExecContext(ctx, "ROLLBACK TO video")
+				err = errors.Join(err, rbErr)
+			}
+		}
+
+		_, relErr := tx.ExecContext(ctx, "RELEASE video")
+		if err = errors.Join(err, relErr); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", f.path, err))
+			continue
+		}
+
+		saved = append(saved, f)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		for _, f := range saved {
+			*errs = append(*errs, fmt.Errorf("%s: unable to commit transaction: %w", f.path, err))
+		}
+		return nil
+	}
+
+	return saved
+}
+
+// importedFiles gets the hash of every file that has already been imported, keyed by path.
+func (yi *YTDLPImporter) importedFiles(ctx context.Context) (map[string]string, error) {
+	rows, err := yi.db.QueryContext(ctx, "SELECT path, hash FROM import_files")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make(map[string]string)
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			return nil, err
+		}
+
+		files[path] = hash
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ytdlpStatements are the prepared statements used to save a video.
+type ytdlpStatements struct {
+	channelID       *sql.Stmt
+	saveVideo       *sql.Stmt
+	videoID         *sql.Stmt
+	saveTag         *sql.Stmt
+	linkTag         *sql.Stmt
+	saveFormat      *sql.Stmt
+	clearTags       *sql.Stmt
+	clearCategories *sql.Stmt
+	clearFormats    *sql.Stmt
+	clearChapters   *sql.Stmt
+	saveChapter     *sql.Stmt
+	clearSubtitles  *sql.Stmt
+	saveSubtitle    *sql.Stmt
+	clearComments   *sql.Stmt
+	saveComment     *sql.Stmt
+	saveFile        *sql.Stmt
+	prepared        []*sql.Stmt
+}
+
+func prepareYTDLPStatements(ctx context.Context, db *sql.DB) (*ytdlpStatements, error) {
+	s := &ytdlpStatements{}
+
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.channelID, "SELECT id FROM channels WHERE youtube_id = ?"},
+		{&s.saveVideo, `INSERT INTO videos(
+		youtube_id,
+		title,
+		full_title,
+		description,
+		channel_id,
+		width,
+		height,
+		resolution,
+		duration,
+		webpage_url,
+		original_url,
+		uploaded_at,
+		availability,
+		epoch,
+		format,
+		format_id,
+		format_note,
+		ext,
+		file_size,
+		tbr,
+		dynamic_range,
+		video_codec,
+		vbr,
+		audio_codec,
+		aspect_ratio,
+		abr,
+		asr,
+		is_archived
+) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,TRUE)
+ON CONFLICT(youtube_id) DO UPDATE SET
+		title = excluded.title,
+		full_title = excluded.full_title,
+		description = excluded.description,
+		width = excluded.width,
+		height = excluded.height,
+		resolution = excluded.resolution,
+		duration = excluded.duration,
+		webpage_url = excluded.webpage_url,
+		original_url = excluded.original_url,
+		uploaded_at = excluded.uploaded_at,
+		availability = excluded.availability,
+		epoch = excluded.epoch,
+		format = excluded.format,
+		format_id = excluded.format_id,
+		format_note = excluded.format_note,
+		ext = excluded.ext,
+		file_size = excluded.file_size,
+		tbr = excluded.tbr,
+		dynamic_range = excluded.dynamic_range,
+		video_codec = excluded.video_codec,
+		vbr = excluded.vbr,
+		audio_codec = excluded.audio_codec,
+		aspect_ratio = excluded.aspect_ratio,
+		abr = excluded.abr,
+		asr = excluded.asr,
+		is_archived = TRUE`},
+		{&s.videoID, "SELECT id FROM videos WHERE youtube_id = ?"},
+		{&s.saveTag, "INSERT INTO video_tags(tag) VALUES(?)"},
+		{&s.linkTag, "INSERT INTO videos_video_tags(video_id, tag_id) SELECT ?, id FROM video_tags WHERE tag = ?"},
+		{&s.saveFormat, `INSERT INTO archived_video_formats(
+			video_id,
+			youtube_format_id,
+			abr,
+			acodec,
+			aspect_ratio,
+			asr,
+			audio_channels,
+			audio_ext,
+			columns,
+			container,
+			dynamic_range,
+			ext,
+			filesize,
+			filesize_approx,
+			format,
+			format_note,
+			fps,
+			has_drm,
+			height,
+			language,
+			quality,
+			resolution,
+			rows,
+			source_preference,
+			tbr,
+			vbr,
+			vcodec,
+			video_ext,
+			width,
+			was_requested)
+			    VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+			ON CONFLICT(video_id, youtube_format_id) DO UPDATE SET was_requested = was_requested OR excluded.was_requested`},
+		{&s.clearTags, "DELETE FROM videos_video_tags WHERE video_id = ?"},
+		{&s.clearCategories, "DELETE FROM videos_video_categories WHERE video_id = ?"},
+		{&s.clearFormats, "DELETE FROM archived_video_formats WHERE video_id = ?"},
+		{&s.clearChapters, "DELETE FROM video_chapters WHERE video_id = ?"},
+		{&s.saveChapter, "INSERT INTO video_chapters(video_id, position, start_time, end_time, title) VALUES(?, ?, ?, ?, ?)"},
+		{&s.clearSubtitles, "DELETE FROM video_subtitles WHERE video_id = ?"},
+		{&s.saveSubtitle, "INSERT INTO video_subtitles(video_id, language, name, formats, is_automatic) VALUES(?, ?, ?, ?, ?)"},
+		{&s.clearComments, "DELETE FROM video_comments WHERE video_id = ?"},
+		{&s.saveComment, `INSERT INTO video_comments(
+			video_id,
+			youtube_id,
+			parent_youtube_id,
+			position,
+			text,
+			author,
+			author_id,
+			author_thumbnail,
+			author_is_uploader,
+			author_is_verified,
+			like_count,
+			is_favorited,
+			is_pinned,
+			published_at) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?)`},
+		{&s.saveFile, `INSERT INTO import_files(path, hash, video_id, imported_at) VALUES(?, ?, ?, ?)
+			ON CONFLICT(path) DO UPDATE SET hash = excluded.hash, video_id = excluded.video_id, imported_at = excluded.imported_at`},
+	}
+
+	for _, q := range queries {
+		stmt, err := db.PrepareContext(ctx, q.query)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		*q.stmt = stmt
+		s.prepared = append(s.prepared, stmt)
+	}
+
+	return s, nil
+}
+
+// Tx returns the statements bound to the transaction.
+func (s *ytdlpStatements) Tx(ctx context.Context, tx *sql.Tx) *ytdlpStatements {
+	return &ytdlpStatements{
+		channelID:       tx.StmtContext(ctx, s.channelID),
+		saveVideo:       tx.StmtContext(ctx, s.saveVideo),
+		videoID:         tx.StmtContext(ctx, s.videoID),
+		saveTag:         tx.StmtContext(ctx, s.saveTag),
+		linkTag:         tx.StmtContext(ctx, s.linkTag),
+		saveFormat:      tx.StmtContext(ctx, s.saveFormat),
+		clearTags:       tx.StmtContext(ctx, s.clearTags),
+		clearCategories: tx.StmtContext(ctx, s.clearCategories),
+		clearFormats:    tx.StmtContext(ctx, s.clearFormats),
+		clearChapters:   tx.StmtContext(ctx, s.clearChapters),
+		saveChapter:     tx.StmtContext(ctx, s.saveChapter),
+		clearSubtitles:  tx.StmtContext(ctx, s.clearSubtitles),
+		saveSubtitle:    tx.StmtContext(ctx, s.saveSubtitle),
+		clearComments:   tx.StmtContext(ctx, s.clearComments),
+		saveComment:     tx.StmtContext(ctx, s.saveComment),
+		saveFile:        tx.StmtContext(ctx, s.saveFile),
+	}
+}
+
+// Close closes the prepared statements. Statements bound to a transaction are closed with the transaction.
+func (s *ytdlpStatements) Close() {
+	for _, stmt := range s.prepared {
+		_ = stmt.Close()
+	}
+}
+
+// saveYTDLPFile saves the video or playlist in the file and records the file as imported.
+func saveYTDLPFile(ctx context.Context, tx DBTX, stmts *ytdlpStatements, f ytdlpFile) error {
+	var videoID *int64
+	switch {
+	case f.video != nil:
+		id, err := saveYTDLPVideo(ctx, tx, stmts, f.video)
+		if err != nil {
+			return err
+		}
+
+		videoID = &id
+	case f.playlist != nil:
+		err := saveYTDLPPlaylist(ctx, tx, f.playlist)
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.path == StdinInput {
+		// there is no way to tell if stdin has changed
+		return nil
+	}
+
+	_, err := stmts.saveFile.ExecContext(ctx, f.path, f.hash, videoID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("unable to record imported file: %w", err)
+	}
+
+	return nil
+}
+
+// saveYTDLPVideo saves a video along with its tags, categories, and formats and returns its database ID. Videos that
+// were already saved are updated, and their tags and formats are replaced.
+func saveYTDLPVideo(ctx context.Context, tx DBTX, stmts *ytdlpStatements, v *YTDLPVideo) (int64, error) {
+	var channelID int64
+	err := stmts.channelID.QueryRowContext(ctx, v.ChannelID).Scan(&channelID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, fmt.Errorf(`%s: missing channel: channel ID = "%s"`, v.YouTubeID, v.ChannelID)
+	case err != nil:
+		return 0, fmt.Errorf(`%s: missing channel: channel ID = "%s": unexpected errror %w`, v.YouTubeID, v.ChannelID, err)
+	}
+
+	_, err = stmts.saveVideo.ExecContext(ctx,
+		v.YouTubeID,
+		v.Title,
+		v.FullTitle,
+		v.Description,
+		channelID,
+		v.Width,
+		v.Height,
+		v.Resolution,
+		v.Duration,
+		v.WebpageURL,
+		v.OriginalURL,
+		v.UploadedAt,
+		v.Availability,
+		v.Epoch,
+		v.Format,
+		v.FormatID,
+		v.FormatNote,
+		v.Ext,
+		v.FileSize,
+		v.TBR,
+		v.DynamicRange,
+		v.VideoCodec,
+		v.VBR,
+		v.AudioCodec,
+		v.AspectRatio,
+		v.ABR,
+		v.ASR)
+	if err != nil {
+		return 0, fmt.Errorf(`%s: failed to save video: %w`, v.YouTubeID, err)
+	}
+
+	var videoID int64
+	err = stmts.videoID.QueryRowContext(ctx, v.YouTubeID).Scan(&videoID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to get database ID: %w", v.YouTubeID, err)
+	}
+
+	// Tags
+	_, err = stmts.clearTags.ExecContext(ctx, videoID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to clear tags: %w", v.YouTubeID, err)
+	}
+
+	for _, t := range v.Tags {
+		_, err := stmts.saveTag.ExecContext(ctx, t)
+		if err != nil {
+			return 0, fmt.Errorf("%s: unable to save tag: %s : %w", v.YouTubeID, t, err)
+		}
+
+		_, err = stmts.linkTag.ExecContext(ctx, videoID, t)
+		if err != nil {
+			return 0, fmt.Errorf("%s: unable to assign tag to video: %s : %w", v.YouTubeID, t, err)
+		}
+	}
+
+	// Categories
+	_, err = stmts.clearCategories.ExecContext(ctx, videoID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to clear categories: %w", v.YouTubeID, err)
+	}
+
+	for _, c := range v.Categories {
+		categoryID, err := VideoCategoryByTitle(ctx, tx, c)
+		if err != nil {
+			return 0, fmt.Errorf("%s: unable to get category ID: %w", v.YouTubeID, err)
+		}
+
+		err = LinkVideoCategory(ctx, tx, videoID, categoryID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: unable to assign category to video: %w", v.YouTubeID, err)
+		}
+	}
+
+	// Formats
+	_, err = stmts.clearFormats.ExecContext(ctx, videoID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to clear formats: %w", v.YouTubeID, err)
+	}
+
+	err = saveYTDLPFormats(ctx, stmts, videoID, v.Formats, false)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to save formats: %w", v.YouTubeID, err)
+	}
+
+	err = saveYTDLPFormats(ctx, stmts, videoID, v.RequestedFormats, true)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to save requested formats: %w", v.YouTubeID, err)
+	}
+
+	// Chapters
+	_, err = stmts.clearChapters.ExecContext(ctx, videoID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to clear chapters: %w", v.YouTubeID, err)
+	}
+
+	for i, c := range v.Chapters {
+		_, err := stmts.saveChapter.ExecContext(ctx, videoID, i, c.StartTime, c.EndTime, c.Title)
+		if err != nil {
+			return 0, fmt.Errorf("%s: unable to save chapter: %s : %w", v.YouTubeID, c.Title, err)
+		}
+	}
+
+	// Subtitles
+	_, err = stmts.clearSubtitles.ExecContext(ctx, videoID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to clear subtitles: %w", v.YouTubeID, err)
+	}
+
+	err = saveYTDLPSubtitles(ctx, stmts, videoID, v.Subtitles, false)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to save subtitles: %w", v.YouTubeID, err)
+	}
+
+	err = saveYTDLPSubtitles(ctx, stmts, videoID, v.AutomaticCaptions, true)
+	if err != nil {
+		return 0, fmt.Errorf("%s: unable to save automatic captions: %w", v.YouTubeID, err)
+	}
+
+	// Comments, files downloaded without --write-comments do not remove comments that were already saved
+	if v.Comments != nil {
+		_, err = stmts.clearComments.ExecContext(ctx, videoID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: unable to clear comments: %w", v.YouTubeID, err)
+		}
+
+		for i, c := range v.Comments {
+			var parent *string
+			if c.Parent != "" && c.Parent != "root" {
+				parent = &c.Parent
+			}
+
+			_, err := stmts.saveComment.ExecContext(ctx, videoID, c.ID, parent, i, c.Text, c.Author, c.AuthorID,
+				c.AuthorThumbnail, c.AuthorIsUploader, c.AuthorIsVerified, c.LikeCount, c.IsFavorited, c.IsPinned, c.Timestamp)
+			if err != nil {
+				return 0, fmt.Errorf("%s: unable to save comment: %s : %w", v.YouTubeID, c.ID, err)
+			}
+		}
+	}
+
+	return videoID, nil
+}
+
+func saveYTDLPSubtitles(ctx context.Context, stmts *ytdlpStatements, videoID int64, subtitles map[string][]YTDLPSubtitle, isAutomatic bool) error {
+	for language, formats := range subtitles {
+		var name string
+		exts := make([]string, 0, len(formats))
+		for _, f := range formats {
+			// automatic captions can be machine translated into every language, which is not worth keeping track of
+			if isAutomatic && strings.Contains(f.URL, "tlang=") {
+				continue
+			}
+
+			if name == "" {
+				name = f.Name
+			}
+			exts = append(exts, f.Ext)
+		}
+
+		if len(exts) == 0 {
+			continue
+		}
+
+		_, err := stmts.saveSubtitle.ExecContext(ctx, videoID, language, name, strings.Join(exts, ","), isAutomatic)
+		if err != nil {
+			return fmt.Errorf("%s: %w", language, err)
+		}
+	}
+
+	return nil
+}
+
+func saveYTDLPFormats(ctx context.Context, stmts *ytdlpStatements, videoID int64, formats []YTDLPFormat, isRequested bool) error {
+	for _, f := range formats {
+		_, err := stmts.saveFormat.ExecContext(ctx,
+			videoID, f.YouTubeFormatID, f.ABR, f.AudioCodec, f.AspectRatio, f.ASR, f.AudioChannels, f.AudioExt,
+			f.Columns, f.Container, f.DynamicRange, f.Ext, f.FileSize, f.FileSizeApprox, f.Format,
+			f.FormatNote, f.FPS, f.HasDRM, f.Height, f.Language, f.Quality, f.Resolution,
+			f.Rows, f.SourcePreference, f.TBR, f.VBR, f.VideoCodec, f.VideoExt, f.Width, isRequested)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readYTDLPFile parses an info.json file. The file is not parsed if its hash matches the hash from when it was last
+// imported. JSON files that are not videos or playlists have a nil video and playlist.
+func readYTDLPFile(src ytdlpSource, importedHash string) ytdlpFile {
+	f := ytdlpFile{path: src.path}
+
+	data, err := src.data, src.err
+	if data == nil && err == nil {
+		data, err = os.ReadFile(src.path)
+	}
+	if err != nil {
+		f.err = err
+		return f
+	}
+
+	sum := sha256.Sum256(data)
+	f.hash = hex.EncodeToString(sum[:])
+	if f.hash == importedHash {
+		f.unchanged = true
+		return f
+	}
+
+	var v YTDLPVideo // consider data dirty because we could have random json files
+	err = json.Unmarshal(data, &v)
+	if err != nil {
+		f.err = err
+		return f
+	}
+
+	switch v.Type {
+	case "video":
+		f.video = &v
+	case "playlist":
+		var p YTDLPPlaylist
+		f.err = json.Unmarshal(data, &p)
+		f.playlist = &p
+	}
+
+	return f
+}
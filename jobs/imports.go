@@ -0,0 +1,448 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+// Import statuses.
+const (
+	ImportRunning     = "running"
+	ImportFinished    = "finished"
+	ImportFailed      = "failed"
+	ImportInterrupted = "interrupted" // the server stopped before the import finished
+)
+
+// Import event types.
+const (
+	EventProgress = "progress"
+	EventMessage  = "message"
+	EventError    = "error"
+	EventFinished = "finished"
+)
+
+var (
+	ErrUnknownImport = errors.New("unknown import")
+	ErrImportRunning = errors.New("an import is already running")
+)
+
+// Import is an import started from the web UI. Times are unix timestamps.
+type Import struct {
+	ID         int64         `json:"id"`
+	Type       string        `json:"type"`
+	Source     string        `json:"source"`
+	Status     string        `json:"status"`
+	Error      string        `json:"error"`
+	ErrorCount int           `json:"error_count"`
+	StartedAt  int64         `json:"started_at"`
+	FinishedAt *int64        `json:"finished_at"`
+	Events     []ImportEvent `json:"events,omitempty"` // only set for a single import
+}
+
+// ImportEvent is an update from an import. Messages and errors are numbered and saved with the import, progress and
+// finished events are only sent while the import is being watched.
+type ImportEvent struct {
+	ID       int64           `json:"id,omitempty"`
+	Type     string          `json:"type"`
+	Message  string          `json:"message,omitempty"`
+	Progress *ImportProgress `json:"progress,omitempty"`
+	Import   *Import         `json:"import,omitempty"` // the summary, sent when the import finishes
+}
+
+// ImportProgress is how far along the current step of an import is. Total is -1 if the number of items is not known.
+type ImportProgress struct {
+	Step  string `json:"step"`
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+}
+
+// Imports runs imports in the background, one at a time, and keeps a history of them. The output of the running import
+// is kept in memory so it can be streamed to the browser, and saved to the database once the import finishes.
+type Imports struct {
+	db   *sql.DB
+	ctx  context.Context
+	lock *Lock
+
+	mu      sync.Mutex
+	running *importRun
+	wg      sync.WaitGroup
+}
+
+// NewImports creates an import runner. Imports are not started while lock is held by a job, and jobs wait for the
+// running import.
+func NewImports(db *sql.DB, lock *Lock) *Imports {
+	return &Imports{db: db, ctx: context.Background(), lock: lock}
+}
+
+// Start marks imports that were still running when the server stopped as interrupted. Imports that are run afterward
+// are stopped when ctx is canceled.
+func (im *Imports) Start(ctx context.Context) error {
+	im.ctx = ctx
+
+	_, err := im.db.ExecContext(ctx, "UPDATE imports SET status = ? WHERE status = ?", ImportInterrupted, ImportRunning)
+	if err != nil {
+		return fmt.Errorf("could not update imports: %w", err)
+	}
+
+	return nil
+}
+
+// Wait waits for the running import to save its history after the context given to Start is canceled.
+func (im *Imports) Wait() {
+	im.wg.Wait()
+}
+
+// Run starts an import in the background. The import reports its progress through the Progress in its context. Only
+// one import runs at a time, since they all write to the same tables. ErrLocked is returned while a job holds the
+// lock.
+func (im *Imports) Run(importType string, source string, run func(ctx context.Context) error) (Import, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if im.running != nil {
+		return Import{}, ErrImportRunning
+	}
+
+	release, err := im.lock.tryLock("import " + importType)
+	if err != nil {
+		return Import{}, err
+	}
+
+	r := &importRun{
+		imp: Import{
+			Type:      importType,
+			Source:    source,
+			Status:    ImportRunning,
+			StartedAt: time.Now().Unix(),
+		},
+		changed: make(chan struct{}),
+	}
+
+	err = im.db.QueryRowContext(im.ctx, "INSERT INTO imports(type, source, status, started_at) VALUES(?, NULLIF(?, ''), ?, ?) RETURNING id",
+		importType, source, ImportRunning, r.imp.StartedAt).Scan(&r.imp.ID)
+	if err != nil {
+		release()
+		return Import{}, fmt.Errorf("could not save import: %w", err)
+	}
+
+	im.running = r
+	im.wg.Add(1)
+	go func() {
+		defer im.wg.Done()
+		defer release()
+		im.run(r, run)
+	}()
+
+	return r.imp, nil
+}
+
+func (im *Imports) run(r *importRun, run func(ctx context.Context) error) {
+	log.Printf("import started: %d (%s)", r.imp.ID, r.imp.Type)
+	runErr := run(importer.WithProgress(im.ctx, r))
+
+	r.mu.Lock()
+	finishedAt := time.Now().Unix()
+	r.imp.FinishedAt = &finishedAt
+	r.imp.Status = ImportFinished
+	if runErr != nil {
+		r.imp.Status = ImportFailed
+		r.imp.Error = runErr.Error()
+	}
+	imp := r.imp
+	importEvents := slices.Clone(r.events)
+	r.mu.Unlock()
+
+	if runErr != nil {
+		log.Printf("import failed: %d (%s): %s", imp.ID, imp.Type, runErr)
+	} else {
+		log.Printf("import finished: %d (%s), %d errors", imp.ID, imp.Type, imp.ErrorCount)
+	}
+
+	// the context is usually canceled because the server is stopping, which should still be recorded
+	err := im.save(context.WithoutCancel(im.ctx), imp, importEvents)
+	if err != nil {
+		log.Printf("unable to save import: %d: %s", imp.ID, err)
+	}
+
+	im.mu.Lock()
+	im.running = nil
+	im.mu.Unlock()
+
+	events.Publish(im.ctx, events.Event{Type: events.ImportFinished, ImportID: imp.ID})
+
+	r.update(func() {
+		r.done = true
+	})
+}
+
+// save saves how the import finished along with its output.
+func (im *Imports) save(ctx context.Context, imp Import, events []ImportEvent) error {
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE imports SET status = ?, error = NULLIF(?, ''), error_count = ?, finished_at = ? WHERE id = ?",
+		imp.Status, imp.Error, imp.ErrorCount, imp.FinishedAt, imp.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		_, err = tx.ExecContext(ctx, "INSERT INTO import_events(import_id, seq, type, message) VALUES(?, ?, ?, ?)",
+			imp.ID, e.ID, e.Type, e.Message)
+		if err != nil {
+			return fmt.Errorf("could not save event: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// List gets the imports, newest first, optionally filtered by type and status.
+func (im *Imports) List(ctx context.Context, importType string, status string) ([]Import, error) {
+	rows, err := im.db.QueryContext(ctx, `SELECT id, type, COALESCE(source, ''), status, COALESCE(error, ''), error_count, started_at, finished_at
+		FROM imports
+		WHERE (? = '' OR type = ?) AND (? = '' OR status = ?)
+		ORDER BY id DESC`, importType, importType, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	running := im.runningImport()
+
+	var imports []Import
+	for rows.Next() {
+		var imp Import
+		err := rows.Scan(&imp.ID, &imp.Type, &imp.Source, &imp.Status, &imp.Error, &imp.ErrorCount, &imp.StartedAt, &imp.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		// the database is only updated once the import finishes
+		if running != nil && running.imp.ID == imp.ID {
+			imp, _ = running.snapshot(0)
+		}
+
+		imports = append(imports, imp)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return imports, nil
+}
+
+// Get gets an import along with its messages and errors.
+func (im *Imports) Get(ctx context.Context, id int64) (Import, error) {
+	if r := im.runningImport(); r != nil && r.imp.ID == id {
+		imp, events := r.snapshot(0)
+		imp.Events = events
+		return imp, nil
+	}
+
+	imp, err := im.load(ctx, id)
+	if err != nil {
+		return imp, err
+	}
+
+	imp.Events, err = im.loadEvents(ctx, id, 0)
+	return imp, err
+}
+
+// Events sends the messages and errors of an import that come after the given event ID, followed by updates until the
+// import finishes or ctx is canceled. The last event is always the finished event with the summary of the import.
+func (im *Imports) Events(ctx context.Context, id int64, after int64, send func(ImportEvent) error) error {
+	r := im.runningImport()
+	if r == nil || r.imp.ID != id {
+		return im.replay(ctx, id, after, send)
+	}
+
+	var sent ImportProgress
+	for {
+		r.mu.Lock()
+		changed := r.changed
+		done := r.done
+		var progress ImportProgress
+		if r.progress != nil {
+			progress = *r.progress
+		}
+		r.mu.Unlock()
+
+		imp, events := r.snapshot(after)
+		for _, e := range events {
+			err := send(e)
+			if err != nil {
+				return err
+			}
+			after = e.ID
+		}
+
+		if progress != sent {
+			sent = progress
+			err := send(ImportEvent{Type: EventProgress, Progress: &progress})
+			if err != nil {
+				return err
+			}
+		}
+
+		if done {
+			return send(ImportEvent{Type: EventFinished, Import: &imp})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// replay sends the saved events of an import that is not running.
+func (im *Imports) replay(ctx context.Context, id int64, after int64, send func(ImportEvent) error) error {
+	imp, err := im.load(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	events, err := im.loadEvents(ctx, id, after)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		err := send(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return send(ImportEvent{Type: EventFinished, Import: &imp})
+}
+
+func (im *Imports) runningImport() *importRun {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	return im.running
+}
+
+func (im *Imports) load(ctx context.Context, id int64) (Import, error) {
+	imp := Import{ID: id}
+	err := im.db.QueryRowContext(ctx, `SELECT type, COALESCE(source, ''), status, COALESCE(error, ''), error_count, started_at, finished_at
+		FROM imports
+		WHERE id = ?`, id).
+		Scan(&imp.Type, &imp.Source, &imp.Status, &imp.Error, &imp.ErrorCount, &imp.StartedAt, &imp.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return imp, ErrUnknownImport
+	}
+
+	return imp, err
+}
+
+func (im *Imports) loadEvents(ctx context.Context, id int64, after int64) ([]ImportEvent, error) {
+	rows, err := im.db.QueryContext(ctx, "SELECT seq, type, message FROM import_events WHERE import_id = ? AND seq > ? ORDER BY seq", id, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ImportEvent
+	for rows.Next() {
+		var e ImportEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Message); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// importRun is the state of the running import. It is the importer.Progress of the import, and wakes up anyone
+// watching the import when something changes.
+type importRun struct {
+	mu       sync.Mutex
+	imp      Import
+	events   []ImportEvent // messages and errors
+	progress *ImportProgress
+	done     bool
+	changed  chan struct{} // closed when something changes
+}
+
+// snapshot copies the import and the events that come after the given event ID.
+func (r *importRun) snapshot(after int64) (Import, []ImportEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.imp, slices.Clone(r.events[min(max(after, 0), int64(len(r.events))):])
+}
+
+func (r *importRun) update(change func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change()
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *importRun) Start(description string, total int64) {
+	r.update(func() {
+		r.progress = &ImportProgress{Step: description, Total: total}
+	})
+}
+
+func (r *importRun) Add(n int) {
+	r.update(func() {
+		if r.progress != nil {
+			r.progress.Done += int64(n)
+		}
+	})
+}
+
+func (r *importRun) Finish() {
+	r.update(func() {
+		if r.progress != nil && r.progress.Total >= 0 {
+			r.progress.Done = r.progress.Total
+		}
+	})
+}
+
+func (r *importRun) Error(err error) {
+	r.update(func() {
+		r.imp.ErrorCount++
+		r.events = append(r.events, ImportEvent{ID: int64(len(r.events) + 1), Type: EventError, Message: err.Error()})
+	})
+}
+
+func (r *importRun) Logf(format string, args ...any) {
+	r.update(func() {
+		r.events = append(r.events, ImportEvent{ID: int64(len(r.events) + 1), Type: EventMessage, Message: fmt.Sprintf(format, args...)})
+	})
+}
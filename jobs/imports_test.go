@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestImportEventsAfter checks that a client reconnecting with Last-Event-ID only gets the messages and errors it
+// missed, both while the import is running and after it was saved.
+func TestImportEventsAfter(t *testing.T) {
+	ctx := context.Background()
+
+	imports := NewImports(openTestDB(t), NewLock())
+	err := imports.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged := make(chan struct{})
+	finish := make(chan struct{})
+	imp, err := imports.Run("takeout", "takeout.zip", func(ctx context.Context) error {
+		// the running import is the Progress in ctx
+		progress := imports.runningImport()
+		progress.Logf("imported %d subscriptions", 3)
+		progress.Error(errors.New("unable to read playlists"))
+		progress.Logf("imported %d watched videos", 10)
+		close(logged)
+
+		<-finish
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-logged
+
+	want := []ImportEvent{
+		{ID: 2, Type: EventError, Message: "unable to read playlists"},
+		{ID: 3, Type: EventMessage, Message: "imported 10 watched videos"},
+	}
+
+	// the import keeps running until the first event is sent, so this is streamed from the running import
+	var running []ImportEvent
+	err = imports.Events(ctx, imp.ID, 1, func(e ImportEvent) error {
+		if len(running) == 0 {
+			close(finish)
+		}
+		running = append(running, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports.Wait()
+
+	checkImportEvents(t, "running", running, want)
+
+	var saved []ImportEvent
+	err = imports.Events(ctx, imp.ID, 1, func(e ImportEvent) error {
+		saved = append(saved, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkImportEvents(t, "saved", saved, want)
+}
+
+// checkImportEvents compares the messages and errors, and checks that the events end with the summary.
+func checkImportEvents(t *testing.T, name string, got []ImportEvent, want []ImportEvent) {
+	t.Helper()
+
+	if len(got) == 0 {
+		t.Fatalf("%s: no events", name)
+	}
+
+	finished := got[len(got)-1]
+	if finished.Type != EventFinished || finished.Import == nil || finished.Import.Status != ImportFinished || finished.Import.ErrorCount != 1 {
+		t.Errorf("%s: last event %+v, want the finished import with 1 error", name, finished)
+	}
+
+	// progress is not numbered, so it is not replayed
+	messages := slices.DeleteFunc(slices.Clone(got[:len(got)-1]), func(e ImportEvent) bool {
+		return e.Type == EventProgress
+	})
+	if !slices.Equal(messages, want) {
+		t.Errorf("%s: events after 1: got %+v, want %+v", name, messages, want)
+	}
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+var ErrLocked = errors.New("a job or import that uses the YouTube Data API is running")
+
+// Lock is shared by the jobs and imports that use the YouTube Data API, so they do not run at the same time and
+// split the day's quota between them. Scheduled jobs wait for the lock, while imports are refused so the browser finds
+// out right away.
+type Lock struct {
+	sem chan struct{}
+
+	mu     sync.Mutex
+	holder string
+}
+
+// NewLock creates a lock that is not held.
+func NewLock() *Lock {
+	return &Lock{sem: make(chan struct{}, 1)}
+}
+
+// Locked returns a job that waits for the lock before running run.
+func (l *Lock) Locked(name string, run Func) Func {
+	return func(ctx context.Context) error {
+		release, err := l.tryLock(name)
+		if errors.Is(err, ErrLocked) {
+			log.Printf("%s: waiting for %s to finish", name, l.holding())
+			release, err = l.lock(ctx, name)
+		}
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return run(ctx)
+	}
+}
+
+// lock waits for the lock until ctx is canceled.
+func (l *Lock) lock(ctx context.Context, name string) (func(), error) {
+	select {
+	case l.sem <- struct{}{}:
+		return l.acquired(name), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tryLock takes the lock if it is free. Otherwise the error has what is holding it.
+func (l *Lock) tryLock(name string) (func(), error) {
+	select {
+	case l.sem <- struct{}{}:
+		return l.acquired(name), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrLocked, l.holding())
+	}
+}
+
+// holding is the name of the job or import holding the lock.
+func (l *Lock) holding() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.holder
+}
+
+// acquired records who holds the lock and returns the function that releases it.
+func (l *Lock) acquired(name string) func() {
+	l.mu.Lock()
+	l.holder = name
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		l.holder = ""
+		l.mu.Unlock()
+
+		<-l.sem
+	}
+}
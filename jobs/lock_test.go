@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockSharedByJobsAndImports(t *testing.T) {
+	ctx := context.Background()
+	lock := NewLock()
+
+	imports := NewImports(openTestDB(t), lock)
+	err := imports.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// imports are refused while a job holds the lock
+	jobStarted := make(chan struct{})
+	finishJob := make(chan struct{})
+	jobDone := make(chan error)
+	go func() {
+		jobDone <- lock.Locked("refresh_stats", func(ctx context.Context) error {
+			close(jobStarted)
+			<-finishJob
+			return nil
+		})(ctx)
+	}()
+	<-jobStarted
+
+	_, err = imports.Run("subscriptions", "", func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Run while refresh_stats is running: got %v, want %v", err, ErrLocked)
+	}
+
+	close(finishJob)
+	if err := <-jobDone; err != nil {
+		t.Fatal(err)
+	}
+
+	// jobs wait for the running import
+	finishImport := make(chan struct{})
+	_, err = imports.Run("subscriptions", "", func(ctx context.Context) error {
+		<-finishImport
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run after refresh_stats finished: %s", err)
+	}
+
+	ran := make(chan struct{})
+	go func() {
+		jobDone <- lock.Locked("import_uploads", func(ctx context.Context) error {
+			close(ran)
+			return nil
+		})(ctx)
+	}()
+
+	select {
+	case <-ran:
+		t.Fatal("import_uploads ran while an import was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(finishImport)
+	if err := <-jobDone; err != nil {
+		t.Fatal(err)
+	}
+	imports.Wait()
+}
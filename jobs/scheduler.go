@@ -0,0 +1,294 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// Func is the work done by a job.
+type Func func(ctx context.Context) error
+
+// Status is the state of a job. Times are unix timestamps.
+type Status struct {
+	Name           string `json:"name"`
+	Interval       int64  `json:"interval"` // seconds, 0 if the job only runs when triggered
+	Running        bool   `json:"running"`
+	LastStartedAt  *int64 `json:"last_started_at"`
+	LastFinishedAt *int64 `json:"last_finished_at"`
+	LastError      string `json:"last_error"`
+	NextRunAt      *int64 `json:"next_run_at"`
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	jitter   time.Duration
+	run      Func
+
+	running atomic.Bool
+	trigger chan struct{}
+}
+
+// Scheduler runs jobs in the background at a regular interval. Each job is run by a single goroutine, so a job never
+// runs more than once at a time, even when it is triggered while a scheduled run is due. The last and next run times
+// are saved in the database, so restarting the server does not restart every schedule.
+type Scheduler struct {
+	db   *sql.DB
+	jobs []*job
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler with no jobs.
+func NewScheduler(db *sql.DB) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+// Add adds a job that runs every interval, plus a random delay of up to jitter so jobs that have the same interval do
+// not all run at once. An interval of 0 means the job only runs when triggered. Jobs have to be added before the
+// scheduler is started.
+func (s *Scheduler) Add(name string, interval time.Duration, jitter time.Duration, run Func) {
+	s.jobs = append(s.jobs, &job{
+		name:     name,
+		interval: interval,
+		jitter:   jitter,
+		run:      run,
+		trigger:  make(chan struct{}, 1),
+	})
+}
+
+// Start runs the jobs until ctx is canceled. Jobs that were due while the server was stopped run right away.
+func (s *Scheduler) Start(ctx context.Context) error {
+	for _, j := range s.jobs {
+		next, err := s.nextRun(ctx, j)
+		if err != nil {
+			return fmt.Errorf("could not load job: %s : %w", j.name, err)
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, j, next)
+		}()
+	}
+
+	return nil
+}
+
+// Wait waits for running jobs to stop after the scheduler's context is canceled.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Trigger runs a job now instead of waiting for its next scheduled run.
+func (s *Scheduler) Trigger(name string) error {
+	j := s.job(name)
+	if j == nil {
+		return ErrUnknownJob
+	}
+
+	if j.running.Load() {
+		return ErrJobRunning
+	}
+
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+		// already triggered, but has not started yet
+	}
+
+	return nil
+}
+
+// Status gets the status of every job, in the order they were added.
+func (s *Scheduler) Status(ctx context.Context) ([]Status, error) {
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status, err := s.status(ctx, j)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// JobStatus gets the status of a single job.
+func (s *Scheduler) JobStatus(ctx context.Context, name string) (Status, error) {
+	j := s.job(name)
+	if j == nil {
+		return Status{}, ErrUnknownJob
+	}
+
+	return s.status(ctx, j)
+}
+
+func (s *Scheduler) job(name string) *job {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) status(ctx context.Context, j *job) (Status, error) {
+	status := Status{
+		Name:     j.name,
+		Interval: int64(j.interval.Seconds()),
+		Running:  j.running.Load(),
+	}
+
+	var lastError sql.NullString
+	err := s.db.QueryRowContext(ctx, "SELECT last_started_at, last_finished_at, last_error, next_run_at FROM jobs WHERE name = ?", j.name).
+		Scan(&status.LastStartedAt, &status.LastFinishedAt, &lastError, &status.NextRunAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return status, err
+	}
+	status.LastError = lastError.String
+
+	return status, nil
+}
+
+// nextRun loads when the job should run next. Jobs that have never run are scheduled after their jitter, so they do not
+// all start with the server.
+func (s *Scheduler) nextRun(ctx context.Context, j *job) (time.Time, error) {
+	if j.interval == 0 {
+		// clear the schedule in case the job used to have an interval
+		return time.Time{}, s.save(ctx, j, "next_run_at = excluded.next_run_at", nil, nil, nil, nil)
+	}
+
+	var nextRunAt sql.NullInt64
+	err := s.db.QueryRowContext(ctx, "SELECT next_run_at FROM jobs WHERE name = ?", j.name).Scan(&nextRunAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || (err == nil && !nextRunAt.Valid):
+		next := time.Now().Add(j.delay(0))
+		return next, s.save(ctx, j, "next_run_at = excluded.next_run_at", nil, nil, nil, next.Unix())
+	case err != nil:
+		return time.Time{}, err
+	}
+
+	return time.Unix(nextRunAt.Int64, 0), nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job, next time.Time) {
+	for {
+		// jobs without an interval wait on a nil channel, which never fires
+		var timer *time.Timer
+		var due <-chan time.Time
+		if j.interval > 0 {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return
+		case <-due:
+		case <-j.trigger:
+			stopTimer(timer)
+		}
+
+		next = s.runJob(ctx, j)
+	}
+}
+
+// runJob runs the job and saves how it went. The time of the next scheduled run is returned.
+func (s *Scheduler) runJob(ctx context.Context, j *job) time.Time {
+	j.running.Store(true)
+	defer j.running.Store(false)
+
+	started := time.Now()
+	err := s.save(ctx, j, "last_started_at = excluded.last_started_at", started.Unix(), nil, nil, nil)
+	if err != nil {
+		log.Printf("unable to save job status: %s: %s", j.name, err)
+	}
+
+	log.Printf("job started: %s", j.name)
+	runErr := j.run(importer.WithProgress(ctx, &jobProgress{name: j.name}))
+
+	var lastError any
+	if runErr != nil {
+		lastError = runErr.Error()
+		log.Printf("job failed: %s: %s", j.name, runErr)
+	} else {
+		log.Printf("job finished: %s (%s)", j.name, time.Since(started).Round(time.Second))
+	}
+
+	var next time.Time
+	var nextRunAt any
+	if j.interval > 0 {
+		next = time.Now().Add(j.delay(j.interval))
+		nextRunAt = next.Unix()
+	}
+
+	// the context is usually canceled because the server is stopping, which should still be recorded
+	err = s.save(context.WithoutCancel(ctx), j, `last_finished_at = excluded.last_finished_at,
+			last_error = excluded.last_error,
+			next_run_at = excluded.next_run_at`, nil, time.Now().Unix(), lastError, nextRunAt)
+	if err != nil {
+		log.Printf("unable to save job status: %s: %s", j.name, err)
+	}
+
+	return next
+}
+
+// save updates the columns in set with the given values.
+func (s *Scheduler) save(ctx context.Context, j *job, set string, lastStartedAt any, lastFinishedAt any, lastError any, nextRunAt any) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO jobs(name, last_started_at, last_finished_at, last_error, next_run_at) VALUES(?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET `+set,
+		j.name, lastStartedAt, lastFinishedAt, lastError, nextRunAt)
+
+	return err
+}
+
+// delay adds a random amount of jitter to d.
+func (j *job) delay(d time.Duration) time.Duration {
+	if j.jitter <= 0 {
+		return d
+	}
+
+	return d + rand.N(j.jitter)
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// jobProgress writes the output of a job to the server log. Progress bars are left out, since nobody is watching them.
+type jobProgress struct {
+	name string
+}
+
+func (jp *jobProgress) Start(description string, total int64) {}
+
+func (jp *jobProgress) Add(n int) {}
+
+func (jp *jobProgress) Finish() {}
+
+func (jp *jobProgress) Error(err error) {
+	log.Printf("%s: ...%s", jp.name, err)
+}
+
+func (jp *jobProgress) Logf(format string, args ...any) {
+	log.Printf("%s: %s", jp.name, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerKeepsNextRun(t *testing.T) {
+	db := openTestDB(t)
+
+	// a job that has never run starts right away
+	ran := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	first := NewScheduler(db)
+	first.Add("poll_feeds", time.Hour, 0, func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	})
+	err := first.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll_feeds did not run")
+	}
+	cancel()
+	first.Wait()
+
+	saved, err := first.JobStatus(context.Background(), "poll_feeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.NextRunAt == nil || saved.LastFinishedAt == nil {
+		t.Fatalf("status after running: %+v, want the finish and next run times", saved)
+	}
+	if next := *saved.NextRunAt - *saved.LastFinishedAt; next < 3599 || next > 3600 {
+		t.Errorf("next run is %ds after the last run, want 1h", next)
+	}
+
+	// restarting keeps the schedule instead of running the job again
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	second := NewScheduler(db)
+	second.Add("poll_feeds", time.Hour, 0, func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	})
+	err = second.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ran:
+		t.Fatal("poll_feeds ran again after restarting")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	restarted, err := second.JobStatus(ctx, "poll_feeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.NextRunAt == nil || *restarted.NextRunAt != *saved.NextRunAt {
+		t.Errorf("next run after restarting: %v, want %d", restarted.NextRunAt, *saved.NextRunAt)
+	}
+
+	cancel()
+	second.Wait()
+}
+
+func TestTriggerWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	runs := 0
+
+	// without an interval the job only runs when triggered
+	scheduler := NewScheduler(openTestDB(t))
+	scheduler.Add("import_uploads", 0, 0, func(ctx context.Context) error {
+		runs++
+		started <- struct{}{}
+		<-finish
+		return errors.New("quota exceeded")
+	})
+	err := scheduler.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = scheduler.Trigger("import_uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	err = scheduler.Trigger("import_uploads")
+	if !errors.Is(err, ErrJobRunning) {
+		t.Errorf("Trigger while running: got %v, want %v", err, ErrJobRunning)
+	}
+
+	status, err := scheduler.JobStatus(ctx, "import_uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running {
+		t.Error("status is not running while the job is running")
+	}
+
+	finish <- struct{}{}
+	cancel()
+	scheduler.Wait()
+
+	if runs != 1 {
+		t.Errorf("job ran %d times, want 1", runs)
+	}
+
+	status, err = scheduler.JobStatus(context.Background(), "import_uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LastError != "quota exceeded" || status.NextRunAt != nil {
+		t.Errorf("status after running: %+v, want the error and no next run", status)
+	}
+
+	err = scheduler.Trigger("refresh_stats")
+	if !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Trigger unknown job: got %v, want %v", err, ErrUnknownJob)
+	}
+}
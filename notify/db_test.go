@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// openTestDB creates a database with every migration applied.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", filepath.Join(t.TempDir(), "youtube.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://../db/migrations", "sqlite3", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) int64 {
+	t.Helper()
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
@@ -0,0 +1,342 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+)
+
+// retryDelays is how long to wait before each retry. A delivery has failed once it runs out of retries.
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 12 * time.Hour}
+
+// retryInterval is how often deliveries that are due to be retried are checked.
+const retryInterval = time.Minute
+
+// deliveryBatchSize is the number of deliveries loaded at a time.
+const deliveryBatchSize = 100
+
+// Notifier sends notifications for the events on the bus that match a rule. Every notification is saved before it is
+// sent, so notifications that could not be delivered are retried, even after the server restarts.
+type Notifier struct {
+	db   *sql.DB
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewNotifier creates a notifier.
+func NewNotifier(db *sql.DB) *Notifier {
+	return &Notifier{
+		db:   db,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Start sends notifications until ctx is canceled.
+func (n *Notifier) Start(ctx context.Context, bus *events.Bus) {
+	n.wg.Add(2)
+
+	go func() {
+		defer n.wg.Done()
+		n.listen(ctx, bus)
+	}()
+
+	go func() {
+		defer n.wg.Done()
+		n.loop(ctx)
+	}()
+}
+
+// Wait waits for the notifier to stop after its context is canceled.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// listen queues notifications for the events on the bus.
+func (n *Notifier) listen(ctx context.Context, bus *events.Bus) {
+	var lastID int64
+	for {
+		ch, unsubscribe := bus.Subscribe(lastID)
+
+	events:
+		for {
+			select {
+			case <-ctx.Done():
+				unsubscribe()
+				return
+			case e, ok := <-ch:
+				if !ok {
+					// fell behind, subscribe again from the last event
+					break events
+				}
+				lastID = e.ID
+
+				err := n.queue(ctx, e)
+				if err != nil {
+					log.Printf("unable to queue notifications: %s", err)
+				}
+			}
+		}
+
+		unsubscribe()
+	}
+}
+
+// queue saves a delivery for every rule that matches the event.
+func (n *Notifier) queue(ctx context.Context, e events.Event) error {
+	queued := false
+	for event, busEvent := range ruleEvents {
+		if busEvent != e.Type {
+			continue
+		}
+
+		rules, err := GetRules(ctx, n.db, event)
+		if err != nil {
+			return fmt.Errorf("could not load rules: %w", err)
+		}
+
+		if len(rules) == 0 {
+			continue
+		}
+
+		notifications, err := n.notifications(ctx, event, e)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rules {
+			for _, notification := range notifications {
+				ok, err := n.matches(ctx, r, notification)
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					continue
+				}
+
+				_, err = n.db.ExecContext(ctx, `INSERT INTO notification_deliveries(rule_id, channel_id, video_id, title, message, url, status, created_at, next_attempt_at)
+					VALUES(?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, NULLIF(?, ''), ?, ?, ?)`,
+					r.ID, notification.ChannelID, notification.VideoID, notification.Title, notification.Message, notification.URL,
+					DeliveryPending, time.Now().Unix(), time.Now().Unix())
+				if err != nil {
+					return fmt.Errorf("could not save delivery: %w", err)
+				}
+				queued = true
+			}
+		}
+	}
+
+	if queued {
+		select {
+		case n.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// notifications creates the notifications for an event, one for each channel or video.
+func (n *Notifier) notifications(ctx context.Context, event string, e events.Event) ([]Notification, error) {
+	var notifications []Notification
+
+	switch event {
+	case RuleNewUpload, RuleArchivedVideoUnavailable:
+		for _, id := range e.VideoIDs {
+			var videoTitle, channelTitle string
+			notification := Notification{Event: event, VideoID: id}
+			err := n.db.QueryRowContext(ctx, `SELECT videos.title, COALESCE(videos.webpage_url, ''), channels.id, channels.title
+				FROM videos
+				JOIN channels ON channels.id = videos.channel_id
+				WHERE videos.id = ? AND (? OR videos.is_archived)`, id, event == RuleNewUpload).
+				Scan(&videoTitle, &notification.URL, &notification.ChannelID, &channelTitle)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not load video: %d : %w", id, err)
+			}
+
+			if event == RuleNewUpload {
+				notification.Title = fmt.Sprintf("New upload from %s", channelTitle)
+				notification.Message = videoTitle
+			} else {
+				notification.Title = "Archived video is no longer available"
+				notification.Message = fmt.Sprintf("%s from %s was made private or deleted.", videoTitle, channelTitle)
+			}
+
+			notifications = append(notifications, notification)
+		}
+	case RuleChannelTerminated:
+		for _, id := range e.ChannelIDs {
+			var title, youtubeID string
+			err := n.db.QueryRowContext(ctx, "SELECT title, youtube_id FROM channels WHERE id = ?", id).Scan(&title, &youtubeID)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not load channel: %d : %w", id, err)
+			}
+
+			notifications = append(notifications, Notification{
+				Event:     event,
+				Title:     "Channel terminated",
+				Message:   fmt.Sprintf("%s was terminated or deleted.", title),
+				URL:       "https://www.youtube.com/channel/" + youtubeID,
+				ChannelID: id,
+			})
+		}
+	}
+
+	return notifications, nil
+}
+
+// matches checks that the notification's channel is in the rule's group.
+func (n *Notifier) matches(ctx context.Context, r Rule, notification Notification) (bool, error) {
+	if !r.groupID.Valid {
+		return true, nil
+	}
+
+	var inGroup bool
+	err := n.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM channel_groups_channels WHERE group_id = ? AND channel_id = ?)",
+		r.groupID.Int64, notification.ChannelID).Scan(&inGroup)
+	if err != nil {
+		return false, fmt.Errorf("could not check group: %w", err)
+	}
+
+	return inGroup, nil
+}
+
+// loop sends deliveries as they are queued, and retries the ones that failed once they are due.
+func (n *Notifier) loop(ctx context.Context) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		err := n.deliver(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("unable to send notifications: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.wake:
+		}
+	}
+}
+
+// pendingDelivery is a delivery that is due along with the sink it is sent to.
+type pendingDelivery struct {
+	id           int64
+	attempts     int
+	sink         string
+	target       string
+	notification Notification
+}
+
+// deliver sends the deliveries that are due.
+func (n *Notifier) deliver(ctx context.Context) error {
+	for {
+		pending, err := n.due(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range pending {
+			sendErr := n.send(ctx, p)
+			if ctx.Err() != nil {
+				// stopped in the middle of sending, so it is sent again next time
+				return ctx.Err()
+			}
+
+			err = n.record(ctx, p, sendErr)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(pending) < deliveryBatchSize {
+			return nil
+		}
+	}
+}
+
+func (n *Notifier) due(ctx context.Context) ([]pendingDelivery, error) {
+	rows, err := n.db.QueryContext(ctx, `SELECT notification_deliveries.id, attempts, sink, target, name, event, COALESCE(channel_id, 0), COALESCE(video_id, 0), title, message, COALESCE(url, '')
+		FROM notification_deliveries
+		JOIN notification_rules ON notification_rules.id = notification_deliveries.rule_id
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, notification_deliveries.id
+		LIMIT ?`, DeliveryPending, time.Now().Unix(), deliveryBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []pendingDelivery
+	for rows.Next() {
+		var p pendingDelivery
+		nt := &p.notification
+		err := rows.Scan(&p.id, &p.attempts, &p.sink, &p.target, &nt.Rule, &nt.Event, &nt.ChannelID, &nt.VideoID, &nt.Title, &nt.Message, &nt.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		pending = append(pending, p)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+func (n *Notifier) send(ctx context.Context, p pendingDelivery) error {
+	sink, err := NewSink(p.sink, p.target)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	return sink.Send(ctx, p.notification)
+}
+
+// record saves the result of sending a delivery, and when to try again if it failed.
+func (n *Notifier) record(ctx context.Context, p pendingDelivery, sendErr error) error {
+	now := time.Now()
+	attempts := p.attempts + 1
+
+	if sendErr == nil {
+		_, err := n.db.ExecContext(ctx, "UPDATE notification_deliveries SET status = ?, attempts = ?, last_error = NULL, next_attempt_at = NULL, delivered_at = ? WHERE id = ?",
+			DeliveryDelivered, attempts, now.Unix(), p.id)
+		return err
+	}
+
+	status := DeliveryFailed
+	var nextAttemptAt any
+	if attempts <= len(retryDelays) {
+		status = DeliveryPending
+		nextAttemptAt = now.Add(retryDelays[attempts-1]).Unix()
+	}
+
+	log.Printf("unable to send notification: %s: %s", p.notification.Rule, sendErr)
+
+	_, err := n.db.ExecContext(ctx, "UPDATE notification_deliveries SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		status, attempts, sendErr.Error(), nextAttemptAt, p.id)
+	return err
+}
@@ -0,0 +1,187 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+)
+
+// testWebhook is a webhook that fails the first failures requests.
+type testWebhook struct {
+	mu       sync.Mutex
+	failures int
+	received []Notification
+}
+
+func (tw *testWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	var n Notification
+	_ = json.NewDecoder(r.Body).Decode(&n)
+	tw.received = append(tw.received, n)
+
+	if len(tw.received) <= tw.failures {
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+	}
+}
+
+func (tw *testWebhook) requests() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	return len(tw.received)
+}
+
+// queueTestDelivery adds a rule that posts to webhook and queues a new upload for it.
+func queueTestDelivery(t *testing.T, db *sql.DB, n *Notifier, rule string, webhook string) {
+	t.Helper()
+	ctx := context.Background()
+
+	channelID := mustExec(t, db, "INSERT INTO channels(youtube_id, title) VALUES(?, ?)", "UC0000000000000000000001", "Channel")
+	videoID := mustExec(t, db, "INSERT INTO videos(youtube_id, channel_id, title, webpage_url) VALUES(?, ?, ?, ?)",
+		"feedvideo01", channelID, "Feed video", "https://www.youtube.com/watch?v=feedvideo01")
+
+	err := AddRule(ctx, db, Rule{Name: rule, Event: RuleNewUpload, Sink: SinkWebhook, Target: webhook})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.queue(ctx, events.Event{Type: events.VideosDiscovered, ChannelIDs: []int64{channelID}, VideoIDs: []int64{videoID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getDelivery gets the only delivery in the log.
+func getDelivery(t *testing.T, db *sql.DB) Delivery {
+	t.Helper()
+
+	deliveries, err := GetDeliveries(context.Background(), db, "", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries, want 1", len(deliveries))
+	}
+
+	return deliveries[0]
+}
+
+// makeDue moves the next attempt of every pending delivery to now, instead of waiting for it.
+func makeDue(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	_, err := db.Exec("UPDATE notification_deliveries SET next_attempt_at = ? WHERE status = ?", time.Now().Unix(), DeliveryPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetrySchedule(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	n := NewNotifier(db)
+
+	webhook := &testWebhook{failures: len(retryDelays) + 1}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	queueTestDelivery(t, db, n, "uploads", server.URL)
+
+	want := []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 12 * time.Hour}
+	for i, delay := range want {
+		before := time.Now().Unix()
+		err := n.deliver(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now().Unix()
+
+		d := getDelivery(t, db)
+		if d.Status != DeliveryPending || d.Attempts != int64(i+1) || !strings.Contains(d.LastError, "500") {
+			t.Fatalf("after attempt %d: %+v, want a pending delivery with the error", i+1, d)
+		}
+		if d.NextAttemptAt == nil || *d.NextAttemptAt < before+int64(delay.Seconds()) || *d.NextAttemptAt > after+int64(delay.Seconds()) {
+			t.Fatalf("after attempt %d: next attempt at %v, want %s later", i+1, d.NextAttemptAt, delay)
+		}
+
+		// not sent again until it is due
+		err = n.deliver(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if webhook.requests() != i+1 {
+			t.Fatalf("after attempt %d: %d requests, want the retry to wait", i+1, webhook.requests())
+		}
+
+		makeDue(t, db)
+	}
+
+	err := n.deliver(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := getDelivery(t, db)
+	if d.Status != DeliveryFailed || d.Attempts != int64(len(want)+1) || d.NextAttemptAt != nil || d.DeliveredAt != nil {
+		t.Errorf("after running out of retries: %+v, want a failed delivery", d)
+	}
+}
+
+func TestRetryThenDeliver(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	n := NewNotifier(db)
+
+	webhook := &testWebhook{failures: 1}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	queueTestDelivery(t, db, n, "uploads", server.URL)
+
+	err := n.deliver(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := getDelivery(t, db)
+	if d.Status != DeliveryPending || d.Attempts != 1 || d.LastError == "" {
+		t.Fatalf("after failing: %+v, want a pending delivery with the error", d)
+	}
+
+	makeDue(t, db)
+	err = n.deliver(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d = getDelivery(t, db)
+	if d.Status != DeliveryDelivered || d.Attempts != 2 || d.LastError != "" || d.NextAttemptAt != nil || d.DeliveredAt == nil {
+		t.Errorf("after retrying: %+v, want a delivered delivery", d)
+	}
+	if d.Rule != "uploads" || d.Title != "New upload from Channel" || d.Message != "Feed video" || d.URL != "https://www.youtube.com/watch?v=feedvideo01" {
+		t.Errorf("delivery log: %+v, want the new upload", d)
+	}
+
+	failed, err := GetDeliveries(ctx, db, "uploads", DeliveryFailed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 0 {
+		t.Errorf("%d failed deliveries, want 0", len(failed))
+	}
+
+	// the retry is the same notification
+	if webhook.requests() != 2 || webhook.received[0] != webhook.received[1] || webhook.received[1].Rule != "uploads" || webhook.received[1].VideoID == 0 {
+		t.Errorf("webhook got %+v, want the same notification twice", webhook.received)
+	}
+}
@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+)
+
+// Rule events.
+const (
+	RuleNewUpload                = "new_upload"                 // a channel uploaded a video
+	RuleChannelTerminated        = "channel_terminated"         // a channel was terminated or deleted
+	RuleArchivedVideoUnavailable = "archived_video_unavailable" // an archived video was made private or deleted
+)
+
+// ruleEvents are the events on the bus that each rule event is sent for.
+var ruleEvents = map[string]string{
+	RuleNewUpload:                events.VideosDiscovered,
+	RuleChannelTerminated:        events.ChannelsDisappeared,
+	RuleArchivedVideoUnavailable: events.VideosUnavailable,
+}
+
+// RuleEvents are the events that can be used in a rule.
+var RuleEvents = []string{RuleNewUpload, RuleChannelTerminated, RuleArchivedVideoUnavailable}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+var ErrUnknownRule = errors.New("unknown rule")
+
+// Rule sends a notification to a sink when an event happens. Rules with a group only apply to the channels in the group.
+type Rule struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Event     string `json:"event"`
+	Group     string `json:"group,omitempty"`
+	Sink      string `json:"sink"`
+	Target    string `json:"target"` // the password is hidden when the rule is loaded
+	CreatedAt int64  `json:"created_at"`
+
+	target  string // the target with the password
+	groupID sql.NullInt64
+}
+
+// Delivery is a notification that was sent, or is waiting to be retried. Times are unix timestamps.
+type Delivery struct {
+	ID            int64  `json:"id"`
+	Rule          string `json:"rule"`
+	Title         string `json:"title"`
+	Message       string `json:"message"`
+	URL           string `json:"url"`
+	Status        string `json:"status"`
+	Attempts      int64  `json:"attempts"`
+	LastError     string `json:"last_error"`
+	CreatedAt     int64  `json:"created_at"`
+	NextAttemptAt *int64 `json:"next_attempt_at"`
+	DeliveredAt   *int64 `json:"delivered_at"`
+}
+
+// AddRule saves a new rule. The group has to exist, and the target is checked by creating the sink.
+func AddRule(ctx context.Context, db *sql.DB, r Rule) error {
+	if !slices.Contains(RuleEvents, r.Event) {
+		return fmt.Errorf("unknown event: %s", r.Event)
+	}
+
+	_, err := NewSink(r.Sink, r.Target)
+	if err != nil {
+		return err
+	}
+
+	var groupID sql.NullInt64
+	if r.Group != "" {
+		err = db.QueryRowContext(ctx, "SELECT id FROM channel_groups WHERE name = ?", r.Group).Scan(&groupID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unknown group: %s", r.Group)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = db.ExecContext(ctx, "INSERT INTO notification_rules(name, event, group_id, sink, target, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+		r.Name, r.Event, groupID, r.Sink, r.Target, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("could not save rule: %s : %w", r.Name, err)
+	}
+
+	return nil
+}
+
+// RemoveRule deletes a rule along with its delivery log.
+func RemoveRule(ctx context.Context, db *sql.DB, name string) error {
+	result, err := db.ExecContext(ctx, "DELETE FROM notification_rules WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err == nil && removed == 0 {
+		return ErrUnknownRule
+	}
+
+	return err
+}
+
+// GetRules gets every rule, or the rules for the given rule event.
+func GetRules(ctx context.Context, db *sql.DB, event string) ([]Rule, error) {
+	rows, err := db.QueryContext(ctx, `SELECT notification_rules.id, notification_rules.name, event, notification_rules.group_id, COALESCE(channel_groups.name, ''), sink, target, created_at
+		FROM notification_rules
+		LEFT JOIN channel_groups ON channel_groups.id = notification_rules.group_id
+		WHERE ? = '' OR event = ?
+		ORDER BY notification_rules.name`, event, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(&r.ID, &r.Name, &r.Event, &r.groupID, &r.Group, &r.Sink, &r.target, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		r.Target = redact(r.target)
+		rules = append(rules, r)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// GetRule gets a rule by name.
+func GetRule(ctx context.Context, db *sql.DB, name string) (Rule, error) {
+	rules, err := GetRules(ctx, db, "")
+	if err != nil {
+		return Rule{}, err
+	}
+
+	for _, r := range rules {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+
+	return Rule{}, ErrUnknownRule
+}
+
+// TestRule sends a test notification with the rule's sink, without retrying or adding it to the delivery log.
+func TestRule(ctx context.Context, r Rule) error {
+	sink, err := NewSink(r.Sink, r.target)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	return sink.Send(ctx, Notification{
+		Rule:    r.Name,
+		Event:   r.Event,
+		Title:   "Test notification",
+		Message: fmt.Sprintf("This is a test of the %s notification rule.", r.Name),
+	})
+}
+
+// GetDeliveries gets the most recent deliveries, newest first, optionally filtered by rule name and status.
+func GetDeliveries(ctx context.Context, db *sql.DB, rule string, status string, limit int) ([]Delivery, error) {
+	rows, err := db.QueryContext(ctx, `SELECT notification_deliveries.id, notification_rules.name, title, message, COALESCE(url, ''), status, attempts, COALESCE(last_error, ''), notification_deliveries.created_at, next_attempt_at, delivered_at
+		FROM notification_deliveries
+		JOIN notification_rules ON notification_rules.id = notification_deliveries.rule_id
+		WHERE (? = '' OR notification_rules.name = ?) AND (? = '' OR status = ?)
+		ORDER BY notification_deliveries.id DESC
+		LIMIT ?`, rule, rule, status, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(&d.ID, &d.Rule, &d.Title, &d.Message, &d.URL, &d.Status, &d.Attempts, &d.LastError, &d.CreatedAt, &d.NextAttemptAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if rerr := rows.Close(); rerr != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// redact hides the password in a target URL.
+func redact(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	return u.Redacted()
+}
@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var testNotification = Notification{
+	Rule:      "uploads",
+	Event:     RuleNewUpload,
+	Title:     "New upload from Café",
+	Message:   "Feed video",
+	URL:       "https://www.youtube.com/watch?v=feedvideo01",
+	ChannelID: 1,
+	VideoID:   2,
+}
+
+func TestWebhookSink(t *testing.T) {
+	var method, contentType string
+	var received Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+
+		err := json.NewDecoder(r.Body).Decode(&received)
+		if err != nil {
+			t.Errorf("could not decode body: %s", err)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkWebhook, server.URL+"/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sink.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPost || contentType != "application/json" {
+		t.Errorf("request: %s with %s, want a JSON POST", method, contentType)
+	}
+	if received != testNotification {
+		t.Errorf("body: %+v, want %+v", received, testNotification)
+	}
+}
+
+func TestNtfySink(t *testing.T) {
+	var header http.Header
+	var body string
+	var user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		user, password, _ = r.BasicAuth()
+
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL + "/my-topic")
+	target.User = url.UserPassword("yt", "secret")
+
+	sink, err := NewSink(SinkNtfy, target.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sink.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body != testNotification.Message {
+		t.Errorf("body: %q, want the message", body)
+	}
+
+	want := map[string]string{
+		"Title": "=?utf-8?q?New_upload_from_Caf=C3=A9?=",
+		"Tags":  RuleNewUpload,
+		"Click": testNotification.URL,
+	}
+	for name, value := range want {
+		if got := header.Get(name); got != value {
+			t.Errorf("%s header: %q, want %q", name, got, value)
+		}
+	}
+
+	if user != "yt" || password != "secret" {
+		t.Errorf("basic auth: %s:%s, want the login from the URL", user, password)
+	}
+}
+
+func TestWebhookSinkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkWebhook, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sink.Send(context.Background(), testNotification)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("got %v, want the unexpected status", err)
+	}
+}
+
+// smtpSession is what the test SMTP server received.
+type smtpSession struct {
+	startedTLS bool
+	auth       string // decoded AUTH PLAIN credentials
+	from       string
+	to         []string
+	data       string
+}
+
+// serveSMTP accepts a single connection and speaks just enough SMTP to receive one message. STARTTLS is offered until
+// it is used, and logging in is only offered over TLS.
+func serveSMTP(t *testing.T, listener net.Listener, config *tls.Config, done chan<- smtpSession) {
+	var session smtpSession
+	defer func() { done <- session }()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Errorf("accept: %s", err)
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...any) {
+		if err := text.PrintfLine(format, args...); err != nil {
+			t.Errorf("reply: %s", err)
+		}
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			t.Errorf("read: %s", err)
+			return
+		}
+
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			if session.startedTLS {
+				reply("250-localhost\r\n250 AUTH PLAIN")
+			} else {
+				reply("250-localhost\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, config)
+			err = tlsConn.Handshake()
+			if err != nil {
+				t.Errorf("TLS handshake: %s", err)
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(tlsConn)
+			session.startedTLS = true
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			session.auth = string(credentials)
+			reply("235 logged in")
+		case "MAIL":
+			session.from = arg
+			reply("250 ok")
+		case "RCPT":
+			session.to = append(session.to, arg)
+			reply("250 ok")
+		case "DATA":
+			reply("354 send the message")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				t.Errorf("read message: %s", err)
+				return
+			}
+			session.data = string(data)
+			reply("250 sent")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command: %s", command)
+		}
+	}
+}
+
+func TestEmailSinkStartTLS(t *testing.T) {
+	// borrow the test certificate for 127.0.0.1 from httptest
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	certServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certServer.Certificate())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	done := make(chan smtpSession, 1)
+	go serveSMTP(t, listener, &tls.Config{Certificates: certServer.TLS.Certificates}, done)
+
+	target := fmt.Sprintf("smtp://yt:secret@%s?from=yt@example.com&to=me@example.com,other@example.com", listener.Addr())
+	sink, err := NewSink(SinkEmail, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.(*emailSink).rootCAs = roots
+
+	err = sink.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := <-done
+	if !session.startedTLS {
+		t.Error("STARTTLS was not used")
+	}
+	if session.auth != "\x00yt\x00secret" {
+		t.Errorf("login: %q, want the user and password from the URL", session.auth)
+	}
+	if session.from != "FROM:<yt@example.com>" {
+		t.Errorf("MAIL %s, want the from address", session.from)
+	}
+	if strings.Join(session.to, " ") != "TO:<me@example.com> TO:<other@example.com>" {
+		t.Errorf("RCPT %v, want both to addresses", session.to)
+	}
+
+	message, err := textproto.NewReader(bufio.NewReader(strings.NewReader(session.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("could not read message headers: %s", err)
+	}
+	if subject := message.Get("Subject"); subject != "=?utf-8?q?New_upload_from_Caf=C3=A9?=" {
+		t.Errorf("Subject: %q, want the encoded title", subject)
+	}
+	if !strings.HasSuffix(session.data, "\nFeed video\n\nhttps://www.youtube.com/watch?v=feedvideo01\n") {
+		t.Errorf("message: %q, want the message and the URL as the body", session.data)
+	}
+}
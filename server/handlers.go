@@ -0,0 +1,1240 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/WileESpaghetti/youtube-subscription-browser/api"
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+	"github.com/WileESpaghetti/youtube-subscription-browser/jobs"
+	"github.com/WileESpaghetti/youtube-subscription-browser/notify"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func jsonError(w http.ResponseWriter, err interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(err)
+}
+
+func getVideoStatsByChannelId(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{} // FIXME single item response
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		// channel_id parameter
+		sChannelID := r.PathValue("id")
+		channelID, err := strconv.Atoi(sChannelID)
+		if len(sChannelID) != 0 && err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "channel_id is invalid",
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		c, err := api.GetChannelVideoStats(r.Context(), db, channelID)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c)
+
+	}
+}
+
+func getAllVideos(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		// channel_id parameter
+		sChannelID := r.URL.Query().Get("channel_id")
+		channelID, err := strconv.Atoi(sChannelID)
+		if len(sChannelID) != 0 && err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "channel_id is invalid",
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		sFrom := r.URL.Query().Get("from")
+		from, err := strconv.Atoi(sFrom)
+		if len(sFrom) != 0 && err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "from field is not a valid timestamp",
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		sTopicID := r.URL.Query().Get("topic_id")
+		topicID, err := strconv.Atoi(sTopicID)
+		if len(sTopicID) != 0 && err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "topic_id is invalid",
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		videos, err := api.GetVideos(r.Context(), db, channelID, from, topicID)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(videos)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, v := range videos {
+			response.Items = append(response.Items, v)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getVideoTopics(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		topics, err := api.GetVideoTopics(r.Context(), db)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(topics)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, t := range topics {
+			response.Items = append(response.Items, t)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getAllChannels(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		channels, err := api.GetChannels(r.Context(), db, r.URL.Query().Get("group"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(channels)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, c := range channels {
+			response.Items = append(response.Items, c)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getMissingChannels(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		channels, err := api.GetMissingChannels(r.Context(), db)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(channels)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, c := range channels {
+			response.Items = append(response.Items, c)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getChannel(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{} // FIXME single item response
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		c, err := api.GetChannel(r.Context(), db, r.PathValue("id"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := api.ItemResponse{Item: c}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// serveMedia serves downloaded thumbnails and banners. Files are named by their contents, so they never change and
+// can be cached forever. Missing files are not cached, since the image might be downloaded later, and directories are
+// not listed.
+func serveMedia(dir string) http.Handler {
+	root := http.Dir(dir)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := root.Open(strings.TrimPrefix(r.URL.Path, "/media"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
+
+func getVideo(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{} // FIXME single item response
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		v, err := api.GetVideo(r.Context(), db, r.PathValue("id"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := api.ItemResponse{Item: v}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func getVideoComments(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		comments, err := api.GetVideoComments(r.Context(), db, r.PathValue("id"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(comments)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, c := range comments {
+			response.Items = append(response.Items, c)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getAllPlaylists(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		// channel_id parameter
+		sChannelID := r.URL.Query().Get("channel_id")
+		channelID, err := strconv.Atoi(sChannelID)
+		if len(sChannelID) != 0 && err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "channel_id is invalid",
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		playlists, err := api.GetPlaylists(r.Context(), db, channelID)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(playlists)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, v := range playlists {
+			response.Items = append(response.Items, v)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getPlaylist(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{} // FIXME single item response
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		p, err := api.GetPlaylist(r.Context(), db, r.PathValue("id"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := api.ItemResponse{Item: p}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func getPlaylistVideos(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		videos, err := api.GetPlaylistVideos(r.Context(), db, r.PathValue("id"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(videos)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, v := range videos {
+			response.Items = append(response.Items, v)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func getChannelGroups(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		groups, err := api.GetChannelGroups(r.Context(), db)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(groups)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, g := range groups {
+			response.Items = append(response.Items, g)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func exportSubscriptions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = api.ExportOPML
+		}
+
+		exportFormat, ok := api.ExportFormats[format]
+		if !ok {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "format is invalid",
+			}
+			jsonError(w, response, http.StatusBadRequest)
+			return
+		}
+
+		group := r.URL.Query().Get("group")
+		channels, err := api.GetChannels(r.Context(), db, group)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		title := "YouTube Subscriptions"
+		if group != "" {
+			title = group
+		}
+
+		w.Header().Set("Content-Type", exportFormat.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "subscriptions"+exportFormat.Extension))
+		err = api.WriteSubscriptions(w, format, title, channels)
+		if err != nil {
+			log.Printf("unable to export subscriptions: %s", err)
+		}
+	}
+}
+
+func getJobs(scheduler *jobs.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		statuses, err := scheduler.Status(r.Context())
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(statuses)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, s := range statuses {
+			response.Items = append(response.Items, s)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// getJob shows the status of a job. POST runs the job now, and responds once it has been started.
+func getJob(scheduler *jobs.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{} // FIXME single item response
+
+		if r.Method != "GET" && r.Method != "POST" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := r.PathValue("name")
+
+		status := http.StatusOK
+		if r.Method == "POST" {
+			err := scheduler.Trigger(name)
+			switch {
+			case errors.Is(err, jobs.ErrJobRunning):
+				response.Error = api.Error{
+					Status: http.StatusConflict,
+					Code:   "CH409",
+					Reason: err.Error(),
+				}
+				jsonError(w, response, http.StatusConflict)
+				return
+			case err != nil && !errors.Is(err, jobs.ErrUnknownJob):
+				response.Error = api.Error{
+					Status: http.StatusMethodNotAllowed,
+					Code:   "CH500",
+					Reason: err.Error(),
+				}
+				jsonError(w, response, http.StatusServiceUnavailable)
+				return
+			}
+			status = http.StatusAccepted
+		}
+
+		job, err := scheduler.JobStatus(r.Context(), name)
+		switch {
+		case errors.Is(err, jobs.ErrUnknownJob):
+			response.Error = api.Error{
+				Status: http.StatusNotFound,
+				Code:   "CH404",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusNotFound)
+			return
+		case err != nil:
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := api.ItemResponse{Item: job}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// Import types that can be started from /api/imports.
+const (
+	importSubscriptions = "subscriptions"
+	importTakeout       = "takeout"
+	importYTDLP         = "ytdlp"
+)
+
+// maxUploadMemory is how much of an uploaded Takeout archive is kept in memory, the rest is written to a temporary file.
+const maxUploadMemory = 32 << 20
+
+// getImports lists the import history. POST starts a new import in the background, and responds once it has been
+// started. The progress of the import is streamed from /api/imports/{id}/events.
+func getImports(imports *jobs.Imports, db *sql.DB, cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" && r.Method != "POST" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		if r.Method == "POST" {
+			startImport(w, r, imports, db, cfg)
+			return
+		}
+
+		history, err := imports.List(r.Context(), r.URL.Query().Get("type"), r.URL.Query().Get("status"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(history)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, i := range history {
+			response.Items = append(response.Items, i)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// startImport starts the import given by the type form field. Takeout archives are uploaded in the file field, and
+// yt-dlp imports read the directory on the server given in the path field.
+func startImport(w http.ResponseWriter, r *http.Request, imports *jobs.Imports, db *sql.DB, cfg Config) {
+	response := api.ListResponse{} // FIXME single item response
+
+	badRequest := func(reason string) {
+		response.Error = api.Error{
+			Status: http.StatusBadRequest,
+			Code:   "CH400",
+			Reason: reason,
+		}
+		jsonError(w, response, http.StatusBadRequest)
+	}
+
+	err := r.ParseMultipartForm(maxUploadMemory)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		badRequest(err.Error())
+		return
+	}
+
+	var source string
+	var run func(ctx context.Context) error
+	var upload string // removed by the import, or here if the import does not start
+
+	importType := r.FormValue("type")
+	switch importType {
+	case importSubscriptions:
+		run = func(ctx context.Context) error {
+			yt, err := newYouTube(ctx, db, cfg, false)
+			if err != nil {
+				return err
+			}
+
+			yi := importer.NewYouTubeImporter(db, yt)
+
+			// a new import is started right after finishing the previous one
+			_, err = yi.Resume(ctx)
+			if err != nil {
+				return err
+			}
+
+			return yi.ImportSubscriptions(ctx)
+		}
+	case importTakeout:
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			badRequest("file is missing")
+			return
+		}
+		defer file.Close()
+
+		// the upload is removed along with the rest of the form once the request is done, so keep a copy for the import
+		archive, err := saveUpload(file)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		source = header.Filename
+		upload = archive
+		run = func(ctx context.Context) error {
+			defer os.Remove(archive)
+			return importer.NewTakeoutImporter(db).Import(ctx, archive)
+		}
+	case importYTDLP:
+		source = r.FormValue("path")
+		info, err := os.Stat(source)
+		if err != nil || !info.IsDir() {
+			badRequest("path is not a directory")
+			return
+		}
+
+		run = func(ctx context.Context) error {
+			return importer.NewYTDLPImporter(db, 0).Import(ctx, source)
+		}
+	default:
+		badRequest("type is invalid")
+		return
+	}
+
+	imp, err := imports.Run(importType, source, run)
+	if err != nil && upload != "" {
+		_ = os.Remove(upload)
+	}
+
+	switch {
+	case errors.Is(err, jobs.ErrImportRunning), errors.Is(err, jobs.ErrLocked):
+		response.Error = api.Error{
+			Status: http.StatusConflict,
+			Code:   "CH409",
+			Reason: err.Error(),
+		}
+		jsonError(w, response, http.StatusConflict)
+		return
+	case err != nil:
+		response.Error = api.Error{
+			Status: http.StatusMethodNotAllowed,
+			Code:   "CH500",
+			Reason: err.Error(),
+		}
+		jsonError(w, response, http.StatusServiceUnavailable)
+		return
+	}
+
+	resp := api.ItemResponse{Item: imp}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/imports/%d", imp.ID))
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// saveUpload copies an uploaded file to a temporary file, and returns its name.
+func saveUpload(upload io.Reader) (string, error) {
+	f, err := os.CreateTemp("", "upload-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("could not save upload: %w", err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, upload)
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("could not save upload: %w", err)
+	}
+
+	return f.Name(), nil
+}
+
+// getImport shows an import along with its messages and errors.
+func getImport(imports *jobs.Imports) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{} // FIXME single item response
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "id is invalid",
+			}
+			jsonError(w, response, http.StatusBadRequest)
+			return
+		}
+
+		imp, err := imports.Get(r.Context(), id)
+		switch {
+		case errors.Is(err, jobs.ErrUnknownImport):
+			response.Error = api.Error{
+				Status: http.StatusNotFound,
+				Code:   "CH404",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusNotFound)
+			return
+		case err != nil:
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := api.ItemResponse{Item: imp}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// streamImportEvents streams the output of an import as server-sent events. Each event's data is a JSON encoded
+// jobs.ImportEvent. Messages and errors have an event ID, so a client that reconnects with the Last-Event-ID header
+// only gets what it missed. The stream ends with a finished event, which has the summary of the import.
+func streamImportEvents(imports *jobs.Imports) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusBadRequest,
+				Code:   "CH400",
+				Reason: "id is invalid",
+			}
+			jsonError(w, response, http.StatusBadRequest)
+			return
+		}
+
+		// a missing or invalid header starts from the beginning
+		lastEventID, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+
+		rc := http.NewResponseController(w)
+		streaming := false
+		err = imports.Events(r.Context(), id, lastEventID, func(e jobs.ImportEvent) error {
+			if !streaming {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				streaming = true
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+
+			if e.ID != 0 {
+				_, _ = fmt.Fprintf(w, "id: %d\n", e.ID)
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			if err != nil {
+				return err
+			}
+
+			return rc.Flush()
+		})
+
+		switch {
+		case streaming || err == nil:
+			// errors after the stream has started are the client going away
+		case errors.Is(err, jobs.ErrUnknownImport):
+			response.Error = api.Error{
+				Status: http.StatusNotFound,
+				Code:   "CH404",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusNotFound)
+		default:
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// streamEvents sends changes to the database as server-sent events, so the dashboard can request what changed instead
+// of polling. Each event's data is a JSON encoded events.Event. A client that reconnects with the Last-Event-ID header
+// gets the events it missed, as long as they are recent.
+func streamEvents(bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		// a missing or invalid header only gets new events
+		lastEventID, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+
+		ch, unsubscribe := bus.Subscribe(lastEventID)
+		defer unsubscribe()
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		_ = rc.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-ch:
+				if !ok {
+					// the client fell behind, it reconnects and picks up from the last event it got
+					return
+				}
+
+				data, err := json.Marshal(e)
+				if err != nil {
+					log.Printf("unable to encode event: %s", err)
+					continue
+				}
+
+				_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+				if err != nil {
+					return
+				}
+
+				if rc.Flush() != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func getNotificationRules(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		rules, err := notify.GetRules(r.Context(), db, r.URL.Query().Get("event"))
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(rules)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, rule := range rules {
+			response.Items = append(response.Items, rule)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// getNotificationDeliveries shows the delivery log, newest first. It can be filtered by rule and status, and defaults
+// to the last 100 notifications.
+func getNotificationDeliveries(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := api.ListResponse{}
+
+		if r.Method != "GET" {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH405",
+				Reason: "method not allowed",
+			}
+			jsonError(w, response, http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 100
+		sLimit := r.URL.Query().Get("limit")
+		if len(sLimit) != 0 {
+			var err error
+			limit, err = strconv.Atoi(sLimit)
+			if err != nil || limit < 1 {
+				response.Error = api.Error{
+					Status: http.StatusBadRequest,
+					Code:   "CH400",
+					Reason: "limit is invalid",
+				}
+				jsonError(w, response, http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		deliveries, err := notify.GetDeliveries(r.Context(), db, r.URL.Query().Get("rule"), r.URL.Query().Get("status"), limit)
+		if err != nil {
+			response.Error = api.Error{
+				Status: http.StatusMethodNotAllowed,
+				Code:   "CH500",
+				Reason: err.Error(),
+			}
+			jsonError(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		total := len(deliveries)
+		response.Page = api.Page{
+			Page:         1,
+			PerPage:      total,
+			TotalPages:   1,
+			TotalRecords: total,
+		}
+
+		// type conversion, probably want to use something fancier than []any in the future
+		for _, d := range deliveries {
+			response.Items = append(response.Items, d)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
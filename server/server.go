@@ -0,0 +1,184 @@
+// Package server serves the API and the frontend, and runs the background jobs and web imports that keep the database
+// up to date.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/WileESpaghetti/youtube-subscription-browser/events"
+	"github.com/WileESpaghetti/youtube-subscription-browser/importer"
+	"github.com/WileESpaghetti/youtube-subscription-browser/jobs"
+	"github.com/WileESpaghetti/youtube-subscription-browser/notify"
+)
+
+// Config is where the server listens, where it keeps its files, and how often each background job runs. An interval
+// of 0 means the job only runs when triggered from /api/jobs.
+type Config struct {
+	Addr        string
+	FrontendDir string
+	MediaDir    string
+	SecretFile  string
+	TokenFile   string
+	Cache       importer.Cache
+	QuotaBudget int
+	FeedURL     string
+
+	PollFeeds        time.Duration
+	RefreshStats     time.Duration
+	ImportUploads    time.Duration
+	RescanMedia      time.Duration
+	MirrorThumbnails time.Duration
+	Jitter           time.Duration
+	MediaLibrary     []string
+}
+
+// Run serves the API until ctx is done, then waits for running jobs and imports to save their status. The database
+// should wait for locks instead of failing right away, since jobs write to it while requests are being served.
+func Run(ctx context.Context, db *sql.DB, cfg Config) error {
+	// jobs and imports publish what they change to the bus
+	bus := events.NewBus()
+	ctx = events.NewContext(ctx, bus)
+
+	// the jobs and imports that use the YouTube Data API take turns
+	lock := jobs.NewLock()
+
+	// start background jobs
+	scheduler := newScheduler(db, cfg, lock)
+	err := scheduler.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer scheduler.Wait()
+
+	// send notifications for the events published by jobs and imports
+	notifier := notify.NewNotifier(db)
+	notifier.Start(ctx, bus)
+	defer notifier.Wait()
+
+	imports := jobs.NewImports(db, lock)
+	err = imports.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer imports.Wait()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(cfg.FrontendDir)))
+	mux.Handle("/media/", serveMedia(cfg.MediaDir))
+
+	mux.Handle("/api/channels", getAllChannels(db))
+	mux.Handle("/api/channels/missing", getMissingChannels(db))
+	mux.Handle("/api/channels/{id}", getChannel(db))
+	mux.Handle("/api/channels/{id}/video_stats", getVideoStatsByChannelId(db))
+	mux.Handle("/api/channel_groups", getChannelGroups(db))
+	mux.Handle("/api/export/subscriptions", exportSubscriptions(db))
+	mux.Handle("/api/videos", getAllVideos(db))
+	mux.Handle("/api/videos/{id}", getVideo(db))
+	mux.Handle("/api/videos/{id}/comments", getVideoComments(db))
+	mux.Handle("/api/video_topics", getVideoTopics(db))
+	mux.Handle("/api/playlists", getAllPlaylists(db))
+	mux.Handle("/api/playlists/{id}", getPlaylist(db))
+	mux.Handle("/api/playlists/{id}/videos", getPlaylistVideos(db))
+	mux.Handle("/api/jobs", getJobs(scheduler))
+	mux.Handle("/api/jobs/{name}", getJob(scheduler))
+	mux.Handle("/api/imports", getImports(imports, db, cfg))
+	mux.Handle("/api/imports/{id}", getImport(imports))
+	mux.Handle("/api/imports/{id}/events", streamImportEvents(imports))
+	mux.Handle("/api/events", streamEvents(bus))
+	mux.Handle("/api/notification_rules", getNotificationRules(db))
+	mux.Handle("/api/notification_deliveries", getNotificationDeliveries(db))
+
+	server := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: mux,
+		// event streams stay open until the client goes away, so they are ended when the server stops
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("Listening on %s...", cfg.Addr)
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// newYouTube creates a YouTube Data API client using the token saved by the command line. A refreshing client requests
+// everything again instead of using the cached responses. Nobody can type in an authorization code while the server is
+// running, so this fails if the command line has not been authorized yet.
+func newYouTube(ctx context.Context, db *sql.DB, cfg Config, refresh bool) (*importer.YouTube, error) {
+	service, err := importer.LoadYouTubeService(ctx, cfg.SecretFile, cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create YouTube client: %w", err)
+	}
+
+	quota, err := importer.NewQuota(ctx, db, cfg.QuotaBudget)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := cfg.Cache
+	if refresh {
+		cache = importer.NewRefreshCache(cache)
+	}
+
+	return importer.NewYouTube(service, cache, quota), nil
+}
+
+func newScheduler(db *sql.DB, cfg Config, lock *jobs.Lock) *jobs.Scheduler {
+	scheduler := jobs.NewScheduler(db)
+
+	scheduler.Add("poll_feeds", cfg.PollFeeds, cfg.Jitter, func(ctx context.Context) error {
+		return importer.NewFeedPoller(db, cfg.FeedURL).Poll(ctx, nil)
+	})
+
+	scheduler.Add("refresh_stats", cfg.RefreshStats, cfg.Jitter, lock.Locked("refresh_stats", func(ctx context.Context) error {
+		// the responses are saved for the other imports, but every channel is requested again
+		yt, err := newYouTube(ctx, db, cfg, true)
+		if err != nil {
+			return err
+		}
+
+		return importer.NewYouTubeImporter(db, yt).RefreshChannels(ctx)
+	}))
+
+	// uses a lot of quota, but finds videos that were made private or deleted
+	scheduler.Add("import_uploads", cfg.ImportUploads, cfg.Jitter, lock.Locked("import_uploads", func(ctx context.Context) error {
+		yt, err := newYouTube(ctx, db, cfg, true)
+		if err != nil {
+			return err
+		}
+
+		return importer.NewYouTubeImporter(db, yt).ImportUploads(ctx, nil)
+	}))
+
+	rescanMedia := cfg.RescanMedia
+	if len(cfg.MediaLibrary) == 0 {
+		rescanMedia = 0
+	}
+	scheduler.Add("rescan_media", rescanMedia, cfg.Jitter, func(ctx context.Context) error {
+		if len(cfg.MediaLibrary) == 0 {
+			return errors.New("no media library directories, use --media-library")
+		}
+
+		return importer.NewMediaLibrary(db).Scan(ctx, false, cfg.MediaLibrary...)
+	})
+
+	scheduler.Add("mirror_thumbnails", cfg.MirrorThumbnails, cfg.Jitter, func(ctx context.Context) error {
+		return importer.NewThumbnailMirror(db, cfg.MediaDir).Mirror(ctx)
+	})
+
+	return scheduler
+}